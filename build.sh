@@ -1,4 +1,6 @@
 #!/bin/bash
 set +e
-CGO_ENABLED=0 GOOS=linux go build -a -o bin/eve-marketwatch ./cmd/
+# cgo for the sqlite3 driver, linked statically to run on scratch
+CGO_ENABLED=1 GOOS=linux go build -a -tags netgo,osusergo,sqlite_omit_load_extension \
+	-ldflags '-linkmode external -extldflags "-static"' -o bin/eve-marketwatch ./cmd/
 docker build -t antihax/eve-marketwatch .
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antihax/eve-marketwatch/marketwatch"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// config for the whole service
+type config struct {
+	// Address for prometheus metrics and pprof
+	MetricsAddress string `yaml:"metrics_address"`
+
+	marketwatch.Config `yaml:",inline"`
+}
+
+// loadConfig builds the config from defaults, then the config file,
+// then environment variables and finally command line flags.
+func loadConfig(args []string) (*config, error) {
+	c := &config{
+		MetricsAddress: ":3000",
+		Config:         marketwatch.DefaultConfig(),
+	}
+
+	flags := flag.NewFlagSet("eve-marketwatch", flag.ContinueOnError)
+	file := flags.String("config", os.Getenv("MARKETWATCH_CONFIG"), "YAML config file")
+	address := flags.String("address", "", "websocket and API listen address")
+	grpcAddress := flags.String("grpc-address", "", "gRPC listen address")
+	esiURL := flags.String("esi-url", "", "ESI host to poll instead of tranquility")
+	metricsAddress := flags.String("metrics-address", "", "metrics and pprof listen address")
+	concurrency := flags.Int("concurrency", 0, "ESI requests in flight at once")
+	snapshot := flags.String("snapshot", "", "file to checkpoint state to")
+	candleDir := flags.String("candle-dir", "", "directory to append closed candles to")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *file != "" {
+		b, err := os.ReadFile(*file)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.UnmarshalStrict(b, c); err != nil {
+			return nil, fmt.Errorf("%s: %v", *file, err)
+		}
+	}
+
+	if err := c.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	// Only flags given on the command line override
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "address":
+			c.Address = *address
+		case "grpc-address":
+			c.GRPCAddress = *grpcAddress
+		case "esi-url":
+			c.ESI.URL = *esiURL
+		case "metrics-address":
+			c.MetricsAddress = *metricsAddress
+		case "concurrency":
+			c.ConcurrentRequests = *concurrency
+		case "snapshot":
+			c.Snapshot = *snapshot
+		case "candle-dir":
+			c.CandleDir = *candleDir
+		}
+	})
+
+	if c.MetricsAddress == "" {
+		return nil, errors.New("metrics_address is required")
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// loadEnv overrides anything set in the environment
+func (c *config) loadEnv() error {
+	envString(&c.RefreshToken, "ESI_REFRESHKEY")
+	envString(&c.ClientID, "ESI_CLIENTID_TOKENSTORE")
+	envString(&c.Secret, "ESI_SECRET_TOKENSTORE")
+	envString(&c.Address, "MARKETWATCH_ADDRESS")
+	envString(&c.GRPCAddress, "MARKETWATCH_GRPC_ADDRESS")
+	envString(&c.ESI.URL, "MARKETWATCH_ESI_URL")
+	envString(&c.ESI.UserAgent, "MARKETWATCH_USER_AGENT")
+	envString(&c.ESI.SSOAuthURL, "MARKETWATCH_SSO_AUTH_URL")
+	envString(&c.ESI.SSOTokenURL, "MARKETWATCH_SSO_TOKEN_URL")
+	envString(&c.MetricsAddress, "MARKETWATCH_METRICS_ADDRESS")
+	envString(&c.Snapshot, "MARKETWATCH_SNAPSHOT")
+	envString(&c.CandleDir, "MARKETWATCH_CANDLE_DIR")
+	envString(&c.Sinks.NDJSON.Dir, "MARKETWATCH_NDJSON_DIR")
+	envString(&c.Sinks.SQL.Driver, "MARKETWATCH_SQL_DRIVER")
+	envString(&c.Sinks.SQL.DSN, "MARKETWATCH_SQL_DSN")
+	if err := envBool(&c.Scope.DisableMarkets, "MARKETWATCH_DISABLE_MARKETS"); err != nil {
+		return err
+	}
+	if err := envBool(&c.Scope.DisableContracts, "MARKETWATCH_DISABLE_CONTRACTS"); err != nil {
+		return err
+	}
+
+	if v := os.Getenv("MARKETWATCH_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("MARKETWATCH_CONCURRENCY: %v", err)
+		}
+		c.ConcurrentRequests = n
+	}
+	if v := os.Getenv("MARKETWATCH_SNAPSHOT_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("MARKETWATCH_SNAPSHOT_INTERVAL: %v", err)
+		}
+		c.SnapshotInterval = d
+	}
+
+	for key, ids := range map[string]*[]int64{
+		"MARKETWATCH_REGIONS":            &c.Scope.Regions,
+		"MARKETWATCH_EXCLUDE_REGIONS":    &c.Scope.ExcludeRegions,
+		"MARKETWATCH_STRUCTURES":         &c.Scope.Structures,
+		"MARKETWATCH_EXCLUDE_STRUCTURES": &c.Scope.ExcludeStructures,
+	} {
+		if err := envIDs(ids, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func envString(v *string, key string) {
+	if e := os.Getenv(key); e != "" {
+		*v = e
+	}
+}
+
+// envBool reads true or false, 1 or 0, from the environment
+func envBool(v *bool, key string) error {
+	e := os.Getenv(key)
+	if e == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(e)
+	if err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	*v = b
+	return nil
+}
+
+// envIDs reads a comma separated list of IDs from the environment
+func envIDs(v *[]int64, key string) error {
+	e := os.Getenv(key)
+	if e == "" {
+		return nil
+	}
+	ids := []int64{}
+	for _, s := range strings.Split(e, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		ids = append(ids, id)
+	}
+	*v = ids
+	return nil
+}
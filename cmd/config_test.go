@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvBool(t *testing.T) {
+	for value, want := range map[string]bool{"true": true, "1": true, "false": false, "0": false} {
+		t.Setenv("MARKETWATCH_DISABLE_MARKETS", value)
+		c, err := loadConfig(nil)
+		if assert.Nil(t, err, value) {
+			assert.Equal(t, want, c.Scope.DisableMarkets, value)
+		}
+	}
+
+	t.Setenv("MARKETWATCH_DISABLE_MARKETS", "yes please")
+	_, err := loadConfig(nil)
+	assert.NotNil(t, err)
+}
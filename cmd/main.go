@@ -1,36 +1,72 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/antihax/eve-marketwatch/marketwatch"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	// Database drivers for the SQL sink
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	log.SetPrefix("eve-marketwatch: ")
 	log.Println("starting eve-marketwatch")
-	mw := marketwatch.NewMarketWatch(
-		os.Getenv("ESI_REFRESHKEY"),
-		os.Getenv("ESI_CLIENTID_TOKENSTORE"),
-		os.Getenv("ESI_SECRET_TOKENSTORE"),
-	)
-	go mw.Run()
+
+	config, err := loadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// Cancelled on SIGINT and SIGTERM to stop everything cleanly
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mw, err := marketwatch.NewMarketWatch(config.Config)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- mw.Run(ctx) }()
 
 	// Run metrics
 	http.Handle("/metrics", promhttp.Handler())
+	metrics := &http.Server{Addr: config.MetricsAddress}
+	go func() {
+		if err := metrics.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalln(err)
+		}
+	}()
 
 	log.Println("started eve-marketwatch")
-	go log.Fatalln(http.ListenAndServe(":3000", nil))
 
 	// Handle SIGINT and SIGTERM.
-	ch := make(chan os.Signal)
+	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
-	log.Println(<-ch)
+	select {
+	case sig := <-ch:
+		log.Println(sig)
+		cancel()
+		err = <-done
+	case err = <-done:
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	metrics.Shutdown(shutdownCtx)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("stopped eve-marketwatch")
 }
@@ -0,0 +1,325 @@
+package marketwatch
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antihax/eve-marketwatch/wsbroadcast"
+	"github.com/antihax/goesi/esi"
+)
+
+const (
+	// Trades returned when no limit is requested
+	defaultTradeLimit = 1000
+
+	// Most trades returned at once
+	maxTradeLimit = 10000
+
+	// Candles returned per series when no limit is requested
+	defaultCandleLimit = 100
+)
+
+// registerAPI adds the read only HTTP API to a mux
+func (s *MarketWatch) registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc("/orders", s.authenticate(s.apiOrders))
+	mux.HandleFunc("/orders/", s.authenticate(s.apiOrder))
+	mux.HandleFunc("/contracts/", s.authenticate(s.apiContract))
+	mux.HandleFunc("/book", s.authenticate(s.apiBook))
+	mux.HandleFunc("/trades", s.authenticate(s.apiTrades))
+	mux.HandleFunc("/candles", s.authenticate(s.apiCandles))
+}
+
+// apiKey is the context key for the API key a request was made with
+type apiKey struct{}
+
+// authenticate requires the same API keys as the websocket when any are configured
+func (s *MarketWatch) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, ok := s.broadcast.Authenticate(w, r)
+		if !ok {
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), apiKey{}, key)))
+	}
+}
+
+// requestKey returns the key a request was made with, nil without keys configured
+func requestKey(ctx context.Context) *wsbroadcast.APIKey {
+	key, _ := ctx.Value(apiKey{}).(*wsbroadcast.APIKey)
+	return key
+}
+
+// allowRegion checks the caller may see a region, structures are region zero
+func allowRegion(ctx context.Context, regionID int64) bool {
+	key := requestKey(ctx)
+	return key == nil || key.Private || regionID != 0
+}
+
+// apiOrders handles GET /orders?region=&location=&type=&is_buy=
+func (s *MarketWatch) apiOrders(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	filter, err := wsbroadcast.ParseFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Empty() {
+		http.Error(w, "at least one of region, location or type is required", http.StatusBadRequest)
+		return
+	}
+
+	var isBuy *bool
+	if v := q.Get("is_buy"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "is_buy: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		isBuy = &b
+	}
+
+	writeJSON(w, s.findOrders(filter, isBuy, allowRegion(r.Context(), 0)))
+}
+
+// apiOrder handles GET /orders/{order_id}
+func (s *MarketWatch) apiOrder(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/orders/"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid order_id", http.StatusBadRequest)
+		return
+	}
+
+	o, ok := s.findOrder(id, allowRegion(r.Context(), 0))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, o.Order)
+}
+
+// apiContract handles GET /contracts/{contract_id}
+func (s *MarketWatch) apiContract(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/contracts/"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid contract_id", http.StatusBadRequest)
+		return
+	}
+
+	c, ok := s.findContract(int32(id))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, c.Contract)
+}
+
+// apiBook handles GET /book?region=&location=&type=&depth=
+func (s *MarketWatch) apiBook(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	filter, err := wsbroadcast.ParseFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(filter.Locations) == 0 && len(filter.Types) == 0 {
+		http.Error(w, "at least one of location or type is required", http.StatusBadRequest)
+		return
+	}
+
+	depth := defaultBookDepth
+	if v := q.Get("depth"); v != "" {
+		depth, err = strconv.Atoi(v)
+		if err != nil || depth < 0 || depth > maxBookDepth {
+			http.Error(w, "depth must be between 0 and "+strconv.Itoa(maxBookDepth), http.StatusBadRequest)
+			return
+		}
+	}
+
+	writeJSON(w, s.books.find(func(regionID, locationID int64, typeID int32) bool {
+		return allowRegion(r.Context(), regionID) && filter.Match(regionID, locationID, int64(typeID))
+	}, depth))
+}
+
+// apiTrades handles GET /trades?region=&location=&type=&since=&limit=
+func (s *MarketWatch) apiTrades(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	filter, err := wsbroadcast.ParseFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var since time.Time
+	if v := q.Get("since"); v != "" {
+		since, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "since: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := defaultTradeLimit
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxTradeLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxTradeLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	writeJSON(w, s.trades.find(func(t Trade) bool {
+		return allowRegion(r.Context(), t.RegionID) && filter.Match(t.RegionID, t.LocationID, int64(t.TypeID))
+	}, since, limit))
+}
+
+// apiCandles handles GET /candles?region=&location=&type=&interval=&limit=
+func (s *MarketWatch) apiCandles(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	filter, err := wsbroadcast.ParseFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(filter.Types) == 0 {
+		http.Error(w, "type is required", http.StatusBadRequest)
+		return
+	}
+
+	interval := q.Get("interval")
+	if interval == "" {
+		interval = "1h"
+	}
+	valid := false
+	for _, i := range candleIntervals {
+		valid = valid || i.name == interval
+	}
+	if !valid {
+		http.Error(w, "interval must be one of 5m, 1h or 1d", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultCandleLimit
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			http.Error(w, "limit must be positive", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Without a location ask for the region candles
+	byRegion := len(filter.Locations) == 0
+	writeJSON(w, s.candles.find(interval, func(c Candle) bool {
+		return (c.LocationID == 0) == byRegion && allowRegion(r.Context(), c.RegionID) &&
+			filter.Match(c.RegionID, c.LocationID, int64(c.TypeID))
+	}, limit))
+}
+
+// findOrders returns all orders matching the filter and side, and structure orders if private.
+// Orders in public structures are in both their region and structure, and are listed once.
+func (s *MarketWatch) findOrders(filter *wsbroadcast.Filter, isBuy *bool, private bool) []esi.GetMarketsRegionIdOrders200Ok {
+	s.mmutex.RLock()
+	defer s.mmutex.RUnlock()
+
+	orders := []esi.GetMarketsRegionIdOrders200Ok{}
+	seen := make(map[int64]bool)
+	for l, r := range s.market {
+		region := s.regionForLocation(l)
+		if !filter.MatchRegion(region) || (region == 0 && !private) {
+			continue
+		}
+		r.Range(func(k, v interface{}) bool {
+			o := v.(Order).Order
+			if filter.MatchLocation(o.LocationId) && filter.MatchType(int64(o.TypeId)) &&
+				(isBuy == nil || *isBuy == o.IsBuyOrder) && !seen[o.OrderId] {
+				seen[o.OrderId] = true
+				orders = append(orders, o)
+			}
+			return true
+		})
+	}
+	return orders
+}
+
+// findOrder looks an order up in every region, then in the structures if private.
+// Orders in public structures are in both, and are found in the region.
+func (s *MarketWatch) findOrder(orderID int64, private bool) (Order, bool) {
+	s.mmutex.RLock()
+	defer s.mmutex.RUnlock()
+	structures := []*sync.Map{}
+	for l, r := range s.market {
+		if s.regionForLocation(l) == 0 {
+			structures = append(structures, r)
+			continue
+		}
+		if v, ok := r.Load(orderID); ok {
+			return v.(Order), true
+		}
+	}
+	if private {
+		for _, r := range structures {
+			if v, ok := r.Load(orderID); ok {
+				return v.(Order), true
+			}
+		}
+	}
+	return Order{}, false
+}
+
+// findContract looks a contract up in every region
+func (s *MarketWatch) findContract(contractID int32) (Contract, bool) {
+	s.cmutex.RLock()
+	defer s.cmutex.RUnlock()
+	for _, r := range s.contracts {
+		if v, ok := r.Load(contractID); ok {
+			return v.(Contract), true
+		}
+	}
+	return Contract{}, false
+}
+
+// allowGet rejects anything but GET requests
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+// writeJSON sends v as the response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,205 @@
+package marketwatch
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/antihax/eve-marketwatch/wsbroadcast"
+	"github.com/antihax/goesi/esi"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	apiRegion    = int64(10000002)
+	apiStructure = int64(1022734985679)
+)
+
+// newAPIServer serves the API of a MarketWatch with a public and a trusted key,
+// holding an order in the region, one in a public structure seen by both the
+// region and the structure, and one only in the structure
+func newAPIServer(t *testing.T) (*MarketWatch, *httptest.Server) {
+	config := DefaultConfig()
+	config.Websocket.Keys = []wsbroadcast.APIKey{
+		{Name: "public", Key: "public-key"},
+		{Name: "trusted", Key: "trusted-key", Private: true},
+	}
+	mw, err := NewMarketWatch(config)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	now := time.Now().UTC()
+	public := testOrder(2, 50, 6)
+	public.LocationId = apiStructure
+	private := testOrder(3, 10, 7)
+	private.LocationId = apiStructure
+	private.IsBuyOrder = true
+
+	mw.createMarketStore(apiRegion)
+	mw.createMarketStore(apiStructure)
+	mw.createStructureState(apiStructure)
+	for _, o := range []esi.GetMarketsRegionIdOrders200Ok{testOrder(1, 100, 5), public} {
+		mw.storeData(apiRegion, Order{Touched: now, Order: o})
+	}
+	for _, o := range []esi.GetMarketsRegionIdOrders200Ok{public, private} {
+		mw.storeData(apiStructure, Order{Touched: now, Order: o})
+	}
+
+	mux := http.NewServeMux()
+	mw.registerAPI(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return mw, server
+}
+
+// getAPI requests a path with a key, decoding the body into v if it succeeds
+func getAPI(t *testing.T, server *httptest.Server, path, key string, v interface{}) int {
+	req, _ := http.NewRequest("GET", server.URL+path, nil)
+	req.Header.Set("X-API-Key", key)
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && v != nil {
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+func TestAPIOrder(t *testing.T) {
+	_, server := newAPIServer(t)
+
+	// Orders in public structures are public, wherever they are found
+	for i := 0; i < 10; i++ {
+		order := esi.GetMarketsRegionIdOrders200Ok{}
+		assert.Equal(t, http.StatusOK, getAPI(t, server, "/orders/2", "public-key", &order))
+		assert.Equal(t, apiStructure, order.LocationId)
+	}
+
+	// Orders only the structure has are private
+	assert.Equal(t, http.StatusNotFound, getAPI(t, server, "/orders/3", "public-key", nil))
+	order := esi.GetMarketsRegionIdOrders200Ok{}
+	assert.Equal(t, http.StatusOK, getAPI(t, server, "/orders/3", "trusted-key", &order))
+	assert.Equal(t, int64(3), order.OrderId)
+
+	assert.Equal(t, http.StatusNotFound, getAPI(t, server, "/orders/4", "trusted-key", nil))
+}
+
+func TestAPIOrders(t *testing.T) {
+	_, server := newAPIServer(t)
+	ids := func(path, key string) []int64 {
+		orders := []esi.GetMarketsRegionIdOrders200Ok{}
+		if !assert.Equal(t, http.StatusOK, getAPI(t, server, path, key, &orders), path) {
+			return nil
+		}
+		found := []int64{}
+		for _, o := range orders {
+			found = append(found, o.OrderId)
+		}
+		return found
+	}
+
+	assert.ElementsMatch(t, []int64{1, 2}, ids("/orders?region=10000002", "public-key"))
+	assert.ElementsMatch(t, []int64{1, 2}, ids("/orders?type=34", "public-key"))
+	assert.ElementsMatch(t, []int64{1}, ids("/orders?location=60003760", "public-key"))
+	assert.ElementsMatch(t, []int64{}, ids("/orders?type=35", "public-key"))
+
+	// Structure markets need a private key, and orders seen in both are listed once
+	assert.ElementsMatch(t, []int64{2}, ids("/orders?location=1022734985679", "public-key"))
+	assert.ElementsMatch(t, []int64{2, 3}, ids("/orders?location=1022734985679", "trusted-key"))
+	assert.ElementsMatch(t, []int64{2, 3}, ids("/orders?region=0", "trusted-key"))
+	assert.ElementsMatch(t, []int64{3}, ids("/orders?type=34&is_buy=true", "trusted-key"))
+	assert.ElementsMatch(t, []int64{}, ids("/orders?type=34&is_buy=true", "public-key"))
+
+	// Bad requests
+	assert.Equal(t, http.StatusBadRequest, getAPI(t, server, "/orders", "public-key", nil))
+	assert.Equal(t, http.StatusBadRequest, getAPI(t, server, "/orders?type=34&is_buy=maybe", "public-key", nil))
+	assert.Equal(t, http.StatusBadRequest, getAPI(t, server, "/orders?type=tritanium", "public-key", nil))
+	assert.Equal(t, http.StatusUnauthorized, getAPI(t, server, "/orders?type=34", "", nil))
+	assert.Equal(t, http.StatusUnauthorized, getAPI(t, server, "/orders?type=34", "wrong-key", nil))
+	resp, err := http.Post(server.URL+"/orders?type=34&key=public-key", "application/json", nil)
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
+
+func TestAPIContract(t *testing.T) {
+	mw, server := newAPIServer(t)
+	contract := FullContract{
+		Contract: esi.GetContractsPublicRegionId200Ok{ContractId: 200, Type_: "item_exchange", Price: 5000, StartLocationId: 60003760},
+		Items:    []esi.GetContractsPublicItemsContractId200Ok{{RecordId: 1, TypeId: 34, Quantity: 1000, IsIncluded: true}},
+	}
+	mw.createContractStore(apiRegion)
+	mw.storeContract(apiRegion, Contract{Touched: time.Now(), Contract: contract})
+
+	found := FullContract{}
+	assert.Equal(t, http.StatusOK, getAPI(t, server, "/contracts/200", "public-key", &found))
+	assert.Equal(t, contract.Contract.ContractId, found.Contract.ContractId)
+	assert.Equal(t, contract.Items, found.Items)
+
+	assert.Equal(t, http.StatusNotFound, getAPI(t, server, "/contracts/201", "public-key", nil))
+	assert.Equal(t, http.StatusBadRequest, getAPI(t, server, "/contracts/abc", "public-key", nil))
+	assert.Equal(t, http.StatusBadRequest, getAPI(t, server, "/contracts/99999999999", "public-key", nil))
+	assert.Equal(t, http.StatusUnauthorized, getAPI(t, server, "/contracts/200", "", nil))
+}
+
+func TestAPITradesAndCandles(t *testing.T) {
+	mw, server := newAPIServer(t)
+	now := time.Now().UTC()
+	trades := []Trade{
+		{OrderID: 1, LocationID: 60003760, RegionID: apiRegion, TypeID: 34, Price: 5, Quantity: 10, Side: "buy", Time: now.Add(-time.Hour)},
+		{OrderID: 3, LocationID: apiStructure, TypeID: 34, Price: 7, Quantity: 5, Side: "sell", Time: now},
+	}
+	mw.trades.add(trades)
+	mw.candles.add(trades)
+
+	find := func(path, key string) []Trade {
+		found := []Trade{}
+		assert.Equal(t, http.StatusOK, getAPI(t, server, path, key, &found), path)
+		return found
+	}
+	assert.Equal(t, trades[:1], find("/trades?type=34", "public-key"))
+	assert.Equal(t, trades, find("/trades?type=34", "trusted-key"))
+	assert.Equal(t, trades[1:], find("/trades?type=34&limit=1", "trusted-key"))
+	assert.Equal(t, trades[1:], find("/trades?since="+now.Add(-time.Minute).Format(time.RFC3339), "trusted-key"))
+	assert.Equal(t, http.StatusBadRequest, getAPI(t, server, "/trades?since=yesterday", "public-key", nil))
+	assert.Equal(t, http.StatusBadRequest, getAPI(t, server, "/trades?limit=0", "public-key", nil))
+
+	// Region candles unless a location is asked for
+	candles := []Candle{}
+	assert.Equal(t, http.StatusOK, getAPI(t, server, "/candles?type=34&interval=5m", "public-key", &candles))
+	if assert.Len(t, candles, 1) {
+		assert.Equal(t, int64(0), candles[0].LocationID)
+		assert.Equal(t, int64(10), candles[0].Volume)
+	}
+	assert.Equal(t, http.StatusOK, getAPI(t, server, "/candles?type=34&location=1022734985679", "public-key", &candles))
+	assert.Len(t, candles, 0)
+	assert.Equal(t, http.StatusOK, getAPI(t, server, "/candles?type=34&location=1022734985679", "trusted-key", &candles))
+	assert.Len(t, candles, 1)
+	assert.Equal(t, http.StatusBadRequest, getAPI(t, server, "/candles?location=60003760", "public-key", nil))
+	assert.Equal(t, http.StatusBadRequest, getAPI(t, server, "/candles?type=34&interval=1w", "public-key", nil))
+}
+
+func TestAPIBook(t *testing.T) {
+	mw, server := newAPIServer(t)
+	private := testOrder(3, 10, 7)
+	private.LocationId = apiStructure
+	mw.books.apply(apiRegion, []esi.GetMarketsRegionIdOrders200Ok{testOrder(1, 100, 5), testOrder(2, 50, 6)}, nil, nil)
+	mw.books.apply(0, []esi.GetMarketsRegionIdOrders200Ok{private}, nil, nil)
+
+	books := []Book{}
+	assert.Equal(t, http.StatusOK, getAPI(t, server, "/book?type=34&depth=1", "public-key", &books))
+	if assert.Len(t, books, 1) {
+		assert.Equal(t, int64(60003760), books[0].LocationID)
+		assert.Len(t, books[0].Sell, 1)
+	}
+	assert.Equal(t, http.StatusOK, getAPI(t, server, "/book?type=34", "trusted-key", &books))
+	assert.Len(t, books, 2)
+	assert.Equal(t, http.StatusBadRequest, getAPI(t, server, "/book?region=10000002", "public-key", nil))
+	assert.Equal(t, http.StatusBadRequest, getAPI(t, server, "/book?type=34&depth=1000", "public-key", nil))
+}
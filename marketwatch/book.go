@@ -0,0 +1,235 @@
+package marketwatch
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/antihax/goesi/esi"
+)
+
+const (
+	// Price levels returned per side when no depth is requested
+	defaultBookDepth = 5
+
+	// Most price levels returned per side
+	maxBookDepth = 100
+)
+
+// PriceLevel is the total volume at one price
+type PriceLevel struct {
+	Price  float64 `json:"price"`
+	Volume int64   `json:"volume"`
+	Orders int     `json:"orders"`
+}
+
+// BookTop is the top of the order book for a type at a location
+type BookTop struct {
+	LocationID  int64     `json:"location_id"`
+	TypeID      int32     `json:"type_id"`
+	BestBuy     float64   `json:"best_buy,omitempty"`
+	BestSell    float64   `json:"best_sell,omitempty"`
+	Spread      float64   `json:"spread,omitempty"`
+	BuyVolume   int64     `json:"buy_volume"`
+	SellVolume  int64     `json:"sell_volume"`
+	BuyOrders   int       `json:"buy_orders"`
+	SellOrders  int       `json:"sell_orders"`
+	TimeChanged time.Time `json:"time_changed"`
+}
+
+// Book is the top of book with a price ladder for each side
+type Book struct {
+	BookTop
+	Buy  []PriceLevel `json:"buy,omitempty"`
+	Sell []PriceLevel `json:"sell,omitempty"`
+}
+
+type bookKey struct {
+	locationID int64
+	typeID     int32
+}
+
+type bookOrder struct {
+	price  float64
+	volume int32
+	isBuy  bool
+}
+
+// typeBook holds the orders for one type at one location
+type typeBook struct {
+	regionID int64
+	orders   map[int64]bookOrder
+	top      BookTop
+}
+
+// orderBooks aggregates all orders by location and type
+type orderBooks struct {
+	mutex sync.RWMutex
+	books map[bookKey]*typeBook
+}
+
+func newOrderBooks() *orderBooks {
+	return &orderBooks{books: make(map[bookKey]*typeBook)}
+}
+
+// apply the results of a poll and return the tops of book that changed
+func (b *orderBooks) apply(regionID int64, additions []esi.GetMarketsRegionIdOrders200Ok, changes, deletions []OrderChange) []BookTop {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	touched := make(map[bookKey]*typeBook)
+	for _, o := range additions {
+		book := b.book(regionID, o.LocationId, o.TypeId)
+		book.orders[o.OrderId] = bookOrder{price: o.Price, volume: o.VolumeRemain, isBuy: o.IsBuyOrder}
+		touched[bookKey{o.LocationId, o.TypeId}] = book
+	}
+	for _, c := range changes {
+		book := b.book(regionID, c.LocationId, c.TypeID)
+		book.orders[c.OrderID] = bookOrder{price: c.Price, volume: c.VolumeRemain, isBuy: c.IsBuyOrder}
+		touched[bookKey{c.LocationId, c.TypeID}] = book
+	}
+	for _, c := range deletions {
+		key := bookKey{c.LocationId, c.TypeID}
+		book, ok := b.books[key]
+		if !ok {
+			continue
+		}
+		delete(book.orders, c.OrderID)
+		touched[key] = book
+	}
+
+	tops := []BookTop{}
+	now := time.Now().UTC()
+	for key, book := range touched {
+		top := book.calculateTop(key)
+		if len(book.orders) == 0 {
+			delete(b.books, key)
+		}
+
+		// Only report real movement
+		top.TimeChanged = book.top.TimeChanged
+		if top == book.top {
+			continue
+		}
+		top.TimeChanged = now
+		book.top = top
+		tops = append(tops, top)
+	}
+	return tops
+}
+
+// book for a location and type, created if needed. Public structures are
+// polled both directly and in their region, the region's poll sets the region.
+func (b *orderBooks) book(regionID, locationID int64, typeID int32) *typeBook {
+	key := bookKey{locationID, typeID}
+	book, ok := b.books[key]
+	if !ok {
+		book = &typeBook{
+			regionID: regionID,
+			orders:   make(map[int64]bookOrder),
+		}
+		b.books[key] = book
+	}
+	if regionID != 0 {
+		book.regionID = regionID
+	}
+	return book
+}
+
+// calculateTop finds the best prices and totals for each side
+func (t *typeBook) calculateTop(key bookKey) BookTop {
+	top := BookTop{LocationID: key.locationID, TypeID: key.typeID}
+	for _, o := range t.orders {
+		if o.isBuy {
+			top.BuyOrders++
+			top.BuyVolume += int64(o.volume)
+			if o.price > top.BestBuy {
+				top.BestBuy = o.price
+			}
+		} else {
+			top.SellOrders++
+			top.SellVolume += int64(o.volume)
+			if top.BestSell == 0 || o.price < top.BestSell {
+				top.BestSell = o.price
+			}
+		}
+	}
+	if top.BuyOrders > 0 && top.SellOrders > 0 {
+		top.Spread = top.BestSell - top.BestBuy
+	}
+	return top
+}
+
+// ladder aggregates each side into price levels, best first
+func (t *typeBook) ladder(depth int) (buy, sell []PriceLevel) {
+	buys := make(map[float64]*PriceLevel)
+	sells := make(map[float64]*PriceLevel)
+	for _, o := range t.orders {
+		side := sells
+		if o.isBuy {
+			side = buys
+		}
+		level, ok := side[o.price]
+		if !ok {
+			level = &PriceLevel{Price: o.price}
+			side[o.price] = level
+		}
+		level.Volume += int64(o.volume)
+		level.Orders++
+	}
+
+	buy = sortLevels(buys, func(a, b float64) bool { return a > b }, depth)
+	sell = sortLevels(sells, func(a, b float64) bool { return a < b }, depth)
+	return buy, sell
+}
+
+func sortLevels(levels map[float64]*PriceLevel, better func(a, b float64) bool, depth int) []PriceLevel {
+	list := make([]PriceLevel, 0, len(levels))
+	for _, l := range levels {
+		list = append(list, *l)
+	}
+	sort.Slice(list, func(i, j int) bool { return better(list[i].Price, list[j].Price) })
+	if len(list) > depth {
+		list = list[:depth]
+	}
+	return list
+}
+
+// find the books matching a filter with up to depth price levels
+func (b *orderBooks) find(match func(regionID, locationID int64, typeID int32) bool, depth int) []Book {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	books := []Book{}
+	for key, book := range b.books {
+		if !match(book.regionID, key.locationID, key.typeID) {
+			continue
+		}
+		full := Book{BookTop: book.top}
+		if depth > 0 {
+			full.Buy, full.Sell = book.ladder(depth)
+		}
+		books = append(books, full)
+	}
+	return books
+}
+
+// tops returns the current tops of book grouped by region
+func (b *orderBooks) tops() map[int64][]BookTop {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	tops := make(map[int64][]BookTop)
+	for _, book := range b.books {
+		tops[book.regionID] = append(tops[book.regionID], book.top)
+	}
+	return tops
+}
+
+// updateBooks applies a poll to the order books and broadcasts the tops that moved
+func (s *MarketWatch) updateBooks(regionID int64, polled time.Time, additions []esi.GetMarketsRegionIdOrders200Ok, changes, deletions []OrderChange) {
+	tops := s.books.apply(regionID, additions, changes, deletions)
+	if len(tops) > 0 {
+		s.publish(BookChanged{RegionID: regionID, Tops: tops, PolledAt: polled})
+	}
+}
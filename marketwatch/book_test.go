@@ -0,0 +1,74 @@
+package marketwatch
+
+import (
+	"testing"
+
+	"github.com/antihax/goesi/esi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderBooks(t *testing.T) {
+	b := newOrderBooks()
+
+	tops := b.apply(10000002, []esi.GetMarketsRegionIdOrders200Ok{
+		{OrderId: 1, LocationId: 60003760, TypeId: 34, Price: 5, VolumeRemain: 100},
+		{OrderId: 2, LocationId: 60003760, TypeId: 34, Price: 6, VolumeRemain: 50},
+		{OrderId: 3, LocationId: 60003760, TypeId: 34, Price: 5, VolumeRemain: 25},
+		{OrderId: 4, LocationId: 60003760, TypeId: 34, Price: 4, VolumeRemain: 1000, IsBuyOrder: true},
+	}, nil, nil)
+	assert.Len(t, tops, 1)
+	assert.Equal(t, 5.0, tops[0].BestSell)
+	assert.Equal(t, 4.0, tops[0].BestBuy)
+	assert.Equal(t, 1.0, tops[0].Spread)
+	assert.Equal(t, int64(175), tops[0].SellVolume)
+	assert.Equal(t, 3, tops[0].SellOrders)
+
+	// Volume behind the best price still changes the totals
+	tops = b.apply(10000002, nil, []OrderChange{
+		{OrderID: 2, LocationId: 60003760, TypeID: 34, Price: 6, VolumeRemain: 40},
+	}, nil)
+	assert.Len(t, tops, 1)
+	assert.Equal(t, int64(165), tops[0].SellVolume)
+
+	// Nothing moved
+	tops = b.apply(10000002, nil, []OrderChange{
+		{OrderID: 2, LocationId: 60003760, TypeID: 34, Price: 6, VolumeRemain: 40},
+	}, nil)
+	assert.Len(t, tops, 0)
+
+	books := b.find(func(regionID, locationID int64, typeID int32) bool { return typeID == 34 }, 2)
+	assert.Len(t, books, 1)
+	assert.Equal(t, []PriceLevel{{Price: 5, Volume: 125, Orders: 2}, {Price: 6, Volume: 40, Orders: 1}}, books[0].Sell)
+	assert.Equal(t, []PriceLevel{{Price: 4, Volume: 1000, Orders: 1}}, books[0].Buy)
+
+	// Removing the best sell moves the top
+	tops = b.apply(10000002, nil, nil, []OrderChange{
+		{OrderID: 1, LocationId: 60003760, TypeID: 34},
+		{OrderID: 3, LocationId: 60003760, TypeID: 34},
+	})
+	assert.Len(t, tops, 1)
+	assert.Equal(t, 6.0, tops[0].BestSell)
+}
+
+func TestOrderBooksPublicStructure(t *testing.T) {
+	const structure = int64(1022734985679)
+	b := newOrderBooks()
+	order := esi.GetMarketsRegionIdOrders200Ok{OrderId: 1, LocationId: structure, TypeId: 34, Price: 5, VolumeRemain: 100}
+	inRegion := func(regionID, locationID int64, typeID int32) bool { return regionID == 10000002 }
+
+	// The structure poll gets there first
+	b.apply(0, []esi.GetMarketsRegionIdOrders200Ok{order}, nil, nil)
+	assert.Len(t, b.find(inRegion, 0), 0)
+
+	// and the region's poll puts the book in the region
+	b.apply(10000002, []esi.GetMarketsRegionIdOrders200Ok{order}, nil, nil)
+	assert.Len(t, b.find(inRegion, 0), 1)
+	assert.Len(t, b.tops()[10000002], 1)
+
+	// where later structure polls leave it
+	b.apply(0, nil, []OrderChange{{OrderID: 1, LocationId: structure, TypeID: 34, Price: 4, VolumeRemain: 100}}, nil)
+	books := b.find(inRegion, 0)
+	if assert.Len(t, books, 1) {
+		assert.Equal(t, 4.0, books[0].BestSell)
+	}
+}
@@ -0,0 +1,398 @@
+package marketwatch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// candleInterval is a candle size and how many closed candles to keep in memory
+type candleInterval struct {
+	name     string
+	duration time.Duration
+	window   int
+}
+
+var candleIntervals = []candleInterval{
+	{"5m", 5 * time.Minute, 288},
+	{"1h", time.Hour, 168},
+	{"1d", 24 * time.Hour, 90},
+}
+
+const (
+	// How often open candles are checked for closing
+	candleCloseInterval = time.Minute
+
+	// How often the retained candles are pruned to the windows kept in memory
+	candlePruneInterval = time.Hour
+)
+
+// Candle is the open, high, low, close and volume of trades in an interval.
+// Region candles have no location, location candles carry their region if known.
+// Location candles are keyed by location alone so a structure seen in both the
+// region and structure polls has a single series.
+type Candle struct {
+	Interval   string    `json:"interval"`
+	RegionID   int64     `json:"region_id,omitempty"`
+	LocationID int64     `json:"location_id,omitempty"`
+	TypeID     int32     `json:"type_id"`
+	Start      time.Time `json:"start"`
+	Open       float64   `json:"open"`
+	High       float64   `json:"high"`
+	Low        float64   `json:"low"`
+	Close      float64   `json:"close"`
+	Volume     int64     `json:"volume"`
+	Trades     int       `json:"trades"`
+	Closed     bool      `json:"closed"`
+}
+
+type candleKey struct {
+	interval   int
+	regionID   int64
+	locationID int64
+	typeID     int32
+}
+
+// candleSeries is the open candle and the recently closed ones
+type candleSeries struct {
+	current *Candle
+	closed  []Candle
+}
+
+// candleStore builds candles from trades
+type candleStore struct {
+	mutex  sync.RWMutex
+	series map[candleKey]*candleSeries
+
+	// optional directory to append closed candles to, and the lock on its files
+	dir   string
+	files sync.Mutex
+}
+
+func newCandleStore(dir string) *candleStore {
+	return &candleStore{series: make(map[candleKey]*candleSeries), dir: dir}
+}
+
+// add trades to the candles, returning any candles closed by them
+func (c *candleStore) add(trades []Trade) []Candle {
+	c.mutex.Lock()
+	closed := []Candle{}
+	for _, t := range trades {
+		for i := range candleIntervals {
+			// Per location and per region, if the region is known.
+			keys := []candleKey{{i, 0, t.LocationID, t.TypeID}}
+			if t.RegionID != 0 {
+				keys = append(keys, candleKey{i, t.RegionID, 0, t.TypeID})
+			}
+			for _, key := range keys {
+				if candle, ok := c.addTrade(key, t); ok {
+					closed = append(closed, candle)
+				}
+			}
+		}
+	}
+	c.mutex.Unlock()
+
+	c.retain(closed)
+	return closed
+}
+
+// addTrade to a series, returning the previous candle if this trade closed it
+func (c *candleStore) addTrade(key candleKey, t Trade) (Candle, bool) {
+	interval := candleIntervals[key.interval]
+	start := t.Time.UTC().Truncate(interval.duration)
+
+	series, ok := c.series[key]
+	if !ok {
+		series = &candleSeries{}
+		c.series[key] = series
+	}
+
+	var closed Candle
+	wasClosed := false
+	if series.current != nil && start.After(series.current.Start) {
+		closed, wasClosed = series.close(interval), true
+	}
+	if (series.current != nil && start.Before(series.current.Start)) ||
+		(series.current == nil && len(series.closed) > 0 && !start.After(series.closed[len(series.closed)-1].Start)) {
+		// Too late for a candle that has already closed
+		return closed, wasClosed
+	}
+
+	if series.current == nil {
+		series.current = &Candle{
+			Interval:   interval.name,
+			RegionID:   t.RegionID,
+			LocationID: key.locationID,
+			TypeID:     key.typeID,
+			Start:      start,
+			Open:       t.Price,
+			High:       t.Price,
+			Low:        t.Price,
+		}
+	}
+
+	candle := series.current
+	if candle.RegionID == 0 {
+		candle.RegionID = t.RegionID
+	}
+	if t.Price > candle.High {
+		candle.High = t.Price
+	}
+	if t.Price < candle.Low {
+		candle.Low = t.Price
+	}
+	candle.Close = t.Price
+	candle.Volume += int64(t.Quantity)
+	candle.Trades++
+
+	return closed, wasClosed
+}
+
+// close the current candle and keep it in the window
+func (s *candleSeries) close(interval candleInterval) Candle {
+	candle := *s.current
+	candle.Closed = true
+	s.current = nil
+	s.closed = append(s.closed, candle)
+	if over := len(s.closed) - interval.window; over > 0 {
+		s.closed = append([]Candle(nil), s.closed[over:]...)
+	}
+	return candle
+}
+
+// closeExpired closes all candles whose interval has passed
+func (c *candleStore) closeExpired(now time.Time) []Candle {
+	c.mutex.Lock()
+	closed := []Candle{}
+	for key, series := range c.series {
+		interval := candleIntervals[key.interval]
+		if series.current != nil && !now.Before(series.current.Start.Add(interval.duration)) {
+			closed = append(closed, series.close(interval))
+		}
+
+		// Forget series that have aged out completely
+		if series.current == nil && len(series.closed) > 0 &&
+			now.Sub(series.closed[len(series.closed)-1].Start) > interval.duration*time.Duration(interval.window) {
+			delete(c.series, key)
+		}
+	}
+	c.mutex.Unlock()
+
+	c.retain(closed)
+	return closed
+}
+
+// find candles for an interval matching the filter, oldest first with the open candle last
+func (c *candleStore) find(interval string, match func(Candle) bool, limit int) []Candle {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	candles := []Candle{}
+	for key, series := range c.series {
+		if candleIntervals[key.interval].name != interval {
+			continue
+		}
+		list := series.closed
+		if series.current != nil {
+			list = append(list[:len(list):len(list)], *series.current)
+		}
+		if len(list) > limit {
+			list = list[len(list)-limit:]
+		}
+		for _, candle := range list {
+			if match(candle) {
+				candles = append(candles, candle)
+			}
+		}
+	}
+	return candles
+}
+
+// candleFile is where closed candles of an interval are retained
+func (c *candleStore) candleFile(interval string) string {
+	return filepath.Join(c.dir, "candles-"+interval+".ndjson")
+}
+
+// retain appends closed candles to one file per interval
+func (c *candleStore) retain(candles []Candle) {
+	if c.dir == "" || len(candles) == 0 {
+		return
+	}
+	c.files.Lock()
+	defer c.files.Unlock()
+
+	files := make(map[string]*os.File)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	for _, candle := range candles {
+		f, ok := files[candle.Interval]
+		if !ok {
+			var err error
+			f, err = os.OpenFile(c.candleFile(candle.Interval), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				log.Printf("failed to retain candles: %v\n", err)
+				return
+			}
+			files[candle.Interval] = f
+		}
+		b, err := json.Marshal(candle)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if _, err := f.Write(append(b, '\n')); err != nil {
+			log.Printf("failed to retain candles: %v\n", err)
+			return
+		}
+	}
+}
+
+// load the retained candles still inside the windows kept in memory, so the
+// history is there again after a restart
+func (c *candleStore) load(now time.Time) {
+	if c.dir == "" {
+		return
+	}
+	c.files.Lock()
+	defer c.files.Unlock()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, interval := range candleIntervals {
+		oldest := now.Add(-interval.duration * time.Duration(interval.window))
+		if err := c.loadCandleFile(i, oldest); err != nil {
+			log.Printf("failed to load candles: %v\n", err)
+		}
+	}
+}
+
+// loadCandleFile adds the closed candles of an interval that started from oldest on.
+// Must hold the mutex.
+func (c *candleStore) loadCandleFile(i int, oldest time.Time) error {
+	interval := candleIntervals[i]
+	f, err := os.Open(c.candleFile(interval.name))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		candle := Candle{}
+		if err := json.Unmarshal(scanner.Bytes(), &candle); err != nil || candle.Start.Before(oldest) {
+			continue
+		}
+		key := candleKey{i, 0, candle.LocationID, candle.TypeID}
+		if candle.LocationID == 0 {
+			key.regionID = candle.RegionID
+		}
+		series, ok := c.series[key]
+		if !ok {
+			series = &candleSeries{}
+			c.series[key] = series
+		}
+		series.closed = append(series.closed, candle)
+		if len(series.closed) > interval.window {
+			series.closed = series.closed[1:]
+		}
+	}
+	return scanner.Err()
+}
+
+// prune drops retained candles older than the window kept in memory for their interval
+func (c *candleStore) prune(now time.Time) {
+	if c.dir == "" {
+		return
+	}
+	c.files.Lock()
+	defer c.files.Unlock()
+
+	for _, interval := range candleIntervals {
+		oldest := now.Add(-interval.duration * time.Duration(interval.window))
+		if err := pruneCandleFile(c.candleFile(interval.name), oldest); err != nil {
+			log.Printf("failed to prune candles: %v\n", err)
+		}
+	}
+}
+
+// pruneCandleFile rewrites a file without the candles that started before oldest,
+// swapping it in place so a crash never loses the rest.
+func pruneCandleFile(path string, oldest time.Time) error {
+	in, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	dropped := 0
+	w := bufio.NewWriter(tmp)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		candle := Candle{}
+		if err := json.Unmarshal(scanner.Bytes(), &candle); err != nil || candle.Start.Before(oldest) {
+			dropped++
+			continue
+		}
+		w.Write(scanner.Bytes())
+		w.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if dropped == 0 {
+		return nil
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// broadcastCandles sends closed candles out grouped by region
+func (s *MarketWatch) broadcastCandles(polled time.Time, candles []Candle) {
+	regions := make(map[int64][]Candle)
+	for _, c := range candles {
+		regions[c.RegionID] = append(regions[c.RegionID], c)
+	}
+	for region, c := range regions {
+		s.publish(CandleClosed{RegionID: region, Candles: c, PolledAt: polled})
+	}
+}
+
+// runCandles closes candles as their intervals pass, and prunes the retained ones
+func (s *MarketWatch) runCandles(ctx context.Context) {
+	pruned := time.Time{}
+	for sleep(ctx, candleCloseInterval) {
+		now := time.Now()
+		s.broadcastCandles(now, s.candles.closeExpired(now))
+		if now.Sub(pruned) >= candlePruneInterval {
+			s.candles.prune(now)
+			pruned = now
+		}
+	}
+}
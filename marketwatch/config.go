@@ -0,0 +1,82 @@
+package marketwatch
+
+import (
+	"errors"
+	"time"
+
+	"github.com/antihax/eve-marketwatch/wsbroadcast"
+)
+
+// Config for the MarketWatch service
+type Config struct {
+	// Address for the websocket and HTTP API
+	Address string `yaml:"address"`
+
+	// Optional address for the gRPC service
+	GRPCAddress string `yaml:"grpc_address"`
+
+	// Where and how to reach ESI
+	ESI ESIOptions `yaml:"esi"`
+
+	// SSO details to poll public structures, structures are skipped if any are missing
+	RefreshToken string `yaml:"refresh_token"`
+	ClientID     string `yaml:"client_id"`
+	Secret       string `yaml:"secret"`
+
+	// Requests in flight to ESI at once
+	ConcurrentRequests int `yaml:"concurrent_requests"`
+
+	// Skip a poll if the cache expires sooner than this after the first page,
+	// and fail it if any other page is closer than MinimumPageWindow.
+	MinimumCacheWindow time.Duration `yaml:"minimum_cache_window"`
+	MinimumPageWindow  time.Duration `yaml:"minimum_page_window"`
+
+	// Optional file to checkpoint state to, and how often
+	Snapshot         string        `yaml:"snapshot"`
+	SnapshotInterval time.Duration `yaml:"snapshot_interval"`
+
+	// Optional directory to append closed candles to, and load them from at startup
+	CandleDir string `yaml:"candle_dir"`
+
+	// What to poll
+	Scope Scope `yaml:"scope"`
+
+	// Websocket settings
+	Websocket wsbroadcast.Config `yaml:"websocket"`
+
+	// Where to durably record every event
+	Sinks SinkConfig `yaml:"sinks"`
+}
+
+// DefaultConfig returns the settings the service has always used
+func DefaultConfig() Config {
+	return Config{
+		Address:            ":3005",
+		ConcurrentRequests: 100, // 100 concurrent requests should fill 1 connection
+		MinimumCacheWindow: 3 * time.Minute,
+		MinimumPageWindow:  20 * time.Second,
+		SnapshotInterval:   5 * time.Minute,
+		Websocket:          wsbroadcast.DefaultConfig(),
+	}
+}
+
+// Validate checks the settings are usable
+func (c *Config) Validate() error {
+	switch {
+	case c.Address == "":
+		return errors.New("address is required")
+	case c.ConcurrentRequests < 1:
+		return errors.New("concurrent_requests must be positive")
+	case c.MinimumCacheWindow < 0 || c.MinimumPageWindow < 0:
+		return errors.New("cache windows cannot be negative")
+	case c.Snapshot != "" && c.SnapshotInterval <= 0:
+		return errors.New("snapshot_interval must be positive")
+	}
+	if err := c.ESI.Validate(); err != nil {
+		return err
+	}
+	if err := c.Sinks.Validate(); err != nil {
+		return err
+	}
+	return c.Websocket.Validate()
+}
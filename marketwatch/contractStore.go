@@ -21,6 +21,15 @@ type FullContract struct {
 	Bids     []esi.GetContractsPublicBidsContractId200Ok  `json:"bids,omitempty"`
 }
 
+// typeIDs lists the item types in the contract
+func (c *FullContract) typeIDs() []int32 {
+	types := make([]int32, 0, len(c.Items))
+	for _, i := range c.Items {
+		types = append(types, i.TypeId)
+	}
+	return types
+}
+
 // ContractChange Details of what changed on an contract
 // Really only price and bids can change
 type ContractChange struct {
@@ -33,6 +42,9 @@ type ContractChange struct {
 	Price       float64                                     `json:"price,omitempty"`
 	Type_       string                                      `json:"type,omitempty"`
 	TimeChanged time.Time                                   `json:"time_changed,omitempty"`
+
+	// item types for filtering, not sent to clients
+	typeIDs []int32
 }
 
 // storeContract returns changes or true if the item is new
@@ -42,19 +54,20 @@ func (s *MarketWatch) storeContract(locationID int64, c Contract) (ContractChang
 		ContractId:  c.Contract.Contract.ContractId,
 		LocationId:  c.Contract.Contract.StartLocationId,
 		TimeChanged: time.Now().UTC(), // We know this was within 30 minutes of this time
+		typeIDs:     c.Contract.typeIDs(),
 	}
 
 	v, loaded := sMap.LoadOrStore(c.Contract.Contract.ContractId, c)
 	if loaded {
 		contract := v.(Contract)
 		if len(contract.Contract.Bids) != len(c.Contract.Bids) {
-			change.Price = contract.Contract.Contract.Price
-			change.Bids = contract.Contract.Bids
-			change.Type_ = contract.Contract.Contract.Type_
-			change.DateExpired = contract.Contract.Contract.DateExpired
+			change.Price = c.Contract.Contract.Price
+			change.Bids = c.Contract.Bids
+			change.Type_ = c.Contract.Contract.Type_
+			change.DateExpired = c.Contract.Contract.DateExpired
 			change.Changed = true
 		}
-		sMap.Store(contract.Contract.Contract.ContractId, contract)
+		sMap.Store(c.Contract.Contract.ContractId, c)
 		return change, false
 	}
 	return change, true
@@ -84,6 +97,7 @@ func (s *MarketWatch) expireContracts(locationID int64, t time.Time) []ContractC
 					Changed:     true,
 					Expired:     expired,
 					TimeChanged: time.Now().UTC(), // We know this was within 30 minutes of this time
+					typeIDs:     o.Contract.typeIDs(),
 				})
 			}
 			return true
@@ -104,9 +118,11 @@ func (s *MarketWatch) getContractStore(locationID int64) *sync.Map {
 	return s.contracts[locationID]
 }
 
-// createContractStore for a location
+// createContractStore for a location, keeping any restored state
 func (s *MarketWatch) createContractStore(locationID int64) {
 	s.cmutex.Lock()
 	defer s.cmutex.Unlock()
-	s.contracts[locationID] = &sync.Map{}
+	if s.contracts[locationID] == nil {
+		s.contracts[locationID] = &sync.Map{}
+	}
 }
@@ -14,12 +14,12 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-func (s *MarketWatch) contractWorker(regionID int32) {
+func (s *MarketWatch) contractWorker(ctx context.Context, regionID int32) {
 	// For totalization
 	wg := sync.WaitGroup{}
 
-	// Loop forever
-	for {
+	// Loop until shutdown
+	for ctx.Err() == nil {
 		start := time.Now()
 		numContracts := 0
 
@@ -28,7 +28,7 @@ func (s *MarketWatch) contractWorker(regionID int32) {
 		echan := make(chan error, 100000)
 
 		contracts, res, err := s.esi.ESI.ContractsApi.GetContractsPublicRegionId(
-			context.Background(), regionID, nil,
+			ctx, regionID, nil,
 		)
 		if err != nil {
 			log.Println(err)
@@ -43,9 +43,9 @@ func (s *MarketWatch) contractWorker(regionID int32) {
 			continue
 		}
 		duration := timeUntilCacheExpires(res)
-		if duration.Minutes() < 3 {
+		if duration < s.config.MinimumCacheWindow {
 			fmt.Printf("%d contract too close to window: waiting %s\n", regionID, duration.String())
-			time.Sleep(duration)
+			sleep(ctx, duration)
 			continue
 		}
 
@@ -56,7 +56,7 @@ func (s *MarketWatch) contractWorker(regionID int32) {
 				defer wg.Done() // release when done
 
 				contracts, r, err := s.esi.ESI.ContractsApi.GetContractsPublicRegionId(
-					context.Background(),
+					ctx,
 					regionID,
 					&esi.GetContractsPublicRegionIdOpts{Page: optional.NewInt32(page)},
 				)
@@ -67,7 +67,7 @@ func (s *MarketWatch) contractWorker(regionID int32) {
 
 				// Are we too close to the end of the window?
 				duration := timeUntilCacheExpires(r)
-				if duration.Seconds() < 20 {
+				if duration < s.config.MinimumPageWindow {
 					echan <- errors.New("contract too close to end of window")
 					return
 				}
@@ -84,9 +84,18 @@ func (s *MarketWatch) contractWorker(regionID int32) {
 		close(rchan)
 		close(echan)
 
+		// Don't report a partial poll as deletions when shutting down
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Start over if any requests failed
+		failed := false
 		for err := range echan {
-			// Start over if any requests failed
 			log.Println(err)
+			failed = true
+		}
+		if failed {
 			continue
 		}
 
@@ -105,15 +114,21 @@ func (s *MarketWatch) contractWorker(regionID int32) {
 				contract := Contract{Touched: start, Contract: FullContract{Contract: o[i]}}
 
 				if o[i].Type_ == "item_exchange" || o[i].Type_ == "auction" {
-					err := s.getContractItems(&contract)
+					err := s.getContractItems(ctx, &contract)
 					if err != nil {
+						if ctx.Err() != nil {
+							return
+						}
 						goto Restart
 					}
 				}
 
 				if o[i].Type_ == "auction" {
-					err := s.getContractBids(&contract)
+					err := s.getContractBids(ctx, &contract)
 					if err != nil {
+						if ctx.Err() != nil {
+							return
+						}
 						goto Restart
 					}
 				}
@@ -138,43 +153,26 @@ func (s *MarketWatch) contractWorker(regionID int32) {
 		).Observe(float64(time.Since(start).Nanoseconds()) / float64(time.Millisecond))
 
 		if len(newContracts) > 0 {
-			s.broadcast.Broadcast(
-				"contract",
-				Message{
-					Action:  "contractAddition",
-					Payload: newContracts,
-				},
-			)
+			s.publish(ContractAdded{RegionID: int64(regionID), Contracts: newContracts, PolledAt: start})
 		}
 
 		// Only bids really change.
 		if len(changes) > 0 {
-			s.broadcast.Broadcast(
-				"contract",
-				Message{
-					Action:  "contractChange",
-					Payload: changes,
-				},
-			)
+			s.publish(ContractChanged{RegionID: int64(regionID), Changes: changes, PolledAt: start})
 		}
 
 		if len(deletions) > 0 {
-			s.broadcast.Broadcast(
-				"contract",
-				Message{
-					Action:  "contractDeletion",
-					Payload: deletions,
-				},
-			)
+			s.publish(ContractDeleted{RegionID: int64(regionID), Deletions: deletions, PolledAt: start})
 		}
+		s.finishPoll("contract", int64(regionID), start)
 
 		// Sleep until the cache timer expires, plus a little.
-		time.Sleep(duration)
+		sleep(ctx, duration)
 	}
 }
 
 // getContractItems for a single contract. Must be prefilled with the contract.
-func (s *MarketWatch) getContractItems(contract *Contract) error {
+func (s *MarketWatch) getContractItems(ctx context.Context, contract *Contract) error {
 	wg := sync.WaitGroup{}
 
 	// Return Channels
@@ -182,7 +180,7 @@ func (s *MarketWatch) getContractItems(contract *Contract) error {
 	echan := make(chan error, 100000)
 
 	items, res, err := s.esi.ESI.ContractsApi.GetContractsPublicItemsContractId(
-		context.Background(), contract.Contract.Contract.ContractId, nil,
+		ctx, contract.Contract.Contract.ContractId, nil,
 	)
 	if err != nil {
 		log.Println(err)
@@ -204,7 +202,7 @@ func (s *MarketWatch) getContractItems(contract *Contract) error {
 			defer wg.Done() // release when done
 
 			items, _, err := s.esi.ESI.ContractsApi.GetContractsPublicItemsContractId(
-				context.Background(),
+				ctx,
 				contract.Contract.Contract.ContractId,
 				&esi.GetContractsPublicItemsContractIdOpts{Page: optional.NewInt32(page)},
 			)
@@ -240,7 +238,7 @@ func (s *MarketWatch) getContractItems(contract *Contract) error {
 }
 
 // getContractBids for a single contract. Must be prefilled with the contract.
-func (s *MarketWatch) getContractBids(contract *Contract) error {
+func (s *MarketWatch) getContractBids(ctx context.Context, contract *Contract) error {
 	wg := sync.WaitGroup{}
 
 	// Return Channels
@@ -248,7 +246,7 @@ func (s *MarketWatch) getContractBids(contract *Contract) error {
 	echan := make(chan error, 100000)
 
 	bids, res, err := s.esi.ESI.ContractsApi.GetContractsPublicBidsContractId(
-		context.Background(), contract.Contract.Contract.ContractId, nil,
+		ctx, contract.Contract.Contract.ContractId, nil,
 	)
 	rchan <- bids
 
@@ -266,7 +264,7 @@ func (s *MarketWatch) getContractBids(contract *Contract) error {
 			defer wg.Done() // release when done
 
 			bids, _, err := s.esi.ESI.ContractsApi.GetContractsPublicBidsContractId(
-				context.Background(),
+				ctx,
 				contract.Contract.Contract.ContractId,
 				&esi.GetContractsPublicBidsContractIdOpts{Page: optional.NewInt32(page)},
 			)
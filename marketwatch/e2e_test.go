@@ -0,0 +1,723 @@
+package marketwatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antihax/eve-marketwatch/schema"
+	"github.com/antihax/eve-marketwatch/wsbroadcast"
+	"github.com/antihax/goesi/esi"
+	"github.com/gorilla/websocket"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+type frame struct {
+	SchemaVersion int             `json:"schema_version"`
+	Action        string          `json:"action"`
+	Sequence      uint64          `json:"seq"`
+	RegionID      int64           `json:"region_id"`
+	Time          time.Time       `json:"time"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// messageSchema is the published schema every message must match
+var messageSchema = jsonschema.MustCompile("../schema/marketwatch.schema.json")
+
+// newTestMarketWatch builds a MarketWatch polling the fake ESI as fast as it will go
+func newTestMarketWatch(t *testing.T, f *fakeESI, config Config) *MarketWatch {
+	padding, retry := cacheExpiryPadding, cacheExpiredRetry
+	cacheExpiryPadding, cacheExpiredRetry = 0, 20*time.Millisecond
+	t.Cleanup(func() { cacheExpiryPadding, cacheExpiredRetry = padding, retry })
+
+	config.Address = "127.0.0.1:0"
+	config.ESI.URL = f.URL
+	config.ESI.SSOTokenURL = f.URL + "/v2/oauth/token"
+	config.MinimumCacheWindow = 0
+	config.MinimumPageWindow = 0
+	mw, err := NewMarketWatch(config)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return mw
+}
+
+// startMarketWatch runs until the test ends
+func startMarketWatch(t *testing.T, mw *MarketWatch) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- mw.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.Nil(t, <-done)
+	})
+}
+
+// runMarketWatch polls the fake ESI until the test ends and connects a websocket client
+func runMarketWatch(t *testing.T, f *fakeESI, config Config, query string) *websocket.Conn {
+	mw := newTestMarketWatch(t, f, config)
+	startMarketWatch(t, mw)
+	return dialMarketWatch(t, mw, query)
+}
+
+// dialMarketWatch connects a websocket client, requesting any subprotocols given
+func dialMarketWatch(t *testing.T, mw *MarketWatch, query string, subprotocols ...string) *websocket.Conn {
+	server := httptest.NewServer(http.HandlerFunc(mw.broadcast.ServeWs))
+	t.Cleanup(server.Close)
+	u := url.URL{Scheme: "ws", Host: server.Listener.Addr().String(), Path: "/", RawQuery: query}
+	dialer := websocket.Dialer{Subprotocols: subprotocols}
+	c, _, err := dialer.Dial(u.String(), nil)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// nextFrame reads the next message, skipping the snapshot markers
+func nextFrame(t *testing.T, c *websocket.Conn) frame {
+	for {
+		f := readFrame(t, c)
+		if f.Action != "snapshotBegin" && f.Action != "snapshotEnd" {
+			return f
+		}
+	}
+}
+
+// readFrame reads the next message, failing the test if none arrives in time
+// or it does not match the schema
+func readFrame(t *testing.T, c *websocket.Conn) frame {
+	c.SetReadDeadline(time.Now().Add(10 * time.Second))
+	_, b, err := c.ReadMessage()
+	if err != nil {
+		t.Fatalf("no message received: %v", err)
+	}
+
+	var v interface{}
+	assert.Nil(t, json.Unmarshal(b, &v))
+	if err := messageSchema.Validate(v); err != nil {
+		t.Errorf("%s does not match the schema: %v", b, err)
+	}
+
+	f := frame{}
+	assert.Nil(t, json.Unmarshal(b, &f))
+	return f
+}
+
+// expectFrame reads the next message and decodes its payload
+func expectFrame(t *testing.T, c *websocket.Conn, action string, payload interface{}) frame {
+	var f frame
+	if action == "snapshotBegin" || action == "snapshotEnd" {
+		f = readFrame(t, c)
+	} else {
+		f = nextFrame(t, c)
+	}
+	if !assert.Equal(t, action, f.Action) {
+		t.FailNow()
+	}
+	if f.SchemaVersion != 0 {
+		assert.False(t, f.Time.IsZero())
+	}
+	assert.Nil(t, json.Unmarshal(f.Payload, payload))
+	return f
+}
+
+func expectOrders(t *testing.T, c *websocket.Conn, action string) []esi.GetMarketsRegionIdOrders200Ok {
+	orders := []esi.GetMarketsRegionIdOrders200Ok{}
+	expectFrame(t, c, action, &orders)
+	sort.Slice(orders, func(i, j int) bool { return orders[i].OrderId < orders[j].OrderId })
+	return orders
+}
+
+func expectOrderChanges(t *testing.T, c *websocket.Conn, action string) []OrderChange {
+	changes := []OrderChange{}
+	expectFrame(t, c, action, &changes)
+	for i := range changes {
+		assert.False(t, changes[i].TimeChanged.IsZero())
+		changes[i].TimeChanged = time.Time{}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].OrderID < changes[j].OrderID })
+	return changes
+}
+
+var testIssued = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func testOrder(orderID int64, volume int32, price float64) esi.GetMarketsRegionIdOrders200Ok {
+	return esi.GetMarketsRegionIdOrders200Ok{
+		OrderId:      orderID,
+		LocationId:   60003760,
+		SystemId:     30000142,
+		TypeId:       34,
+		Duration:     90,
+		Issued:       testIssued,
+		MinVolume:    1,
+		Range_:       "region",
+		Price:        price,
+		VolumeRemain: volume,
+		VolumeTotal:  100,
+	}
+}
+
+func TestEndToEndMarket(t *testing.T) {
+	const region = int32(10000002)
+	path := fmt.Sprintf("/v1/markets/%d/orders/", region)
+
+	f := newFakeESI()
+	defer f.Close()
+	f.setRegions(region, 11000001)
+
+	// Two pages, the second hits a server error first and is retried
+	f.setOrders(region, testOrder(1, 100, 5), testOrder(2, 50, 6), testOrder(3, 10, 7))
+	f.fail(path, 2, http.StatusBadGateway)
+
+	config := DefaultConfig()
+	config.Scope.DisableContracts = true
+	c := runMarketWatch(t, f, config, "market=1")
+
+	assert.Equal(t,
+		[]esi.GetMarketsRegionIdOrders200Ok{testOrder(1, 100, 5), testOrder(2, 50, 6), testOrder(3, 10, 7)},
+		expectOrders(t, c, "addition"),
+	)
+	assert.False(t, f.failing())
+
+	// A new order and a partial fill
+	f.setOrders(region, testOrder(1, 80, 5), testOrder(2, 50, 6), testOrder(3, 10, 7), testOrder(4, 100, 8))
+	assert.Equal(t,
+		[]esi.GetMarketsRegionIdOrders200Ok{testOrder(4, 100, 8)},
+		expectOrders(t, c, "addition"),
+	)
+	assert.Equal(t,
+		[]OrderChange{{
+			OrderID:      1,
+			LocationId:   60003760,
+			TypeID:       34,
+			VolumeChange: 20,
+			VolumeRemain: 80,
+			Price:        5,
+			Duration:     90,
+			Issued:       testIssued,
+		}},
+		expectOrderChanges(t, c, "change"),
+	)
+
+	// A poll with a missing page must not delete the orders on it
+	f.fail(path, 2, http.StatusNotFound)
+	for f.failing() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// An order disappears
+	f.setOrders(region, testOrder(1, 80, 5), testOrder(3, 10, 7), testOrder(4, 100, 8))
+	assert.Equal(t,
+		[]OrderChange{{
+			OrderID:      2,
+			LocationId:   60003760,
+			TypeID:       34,
+			VolumeChange: 50,
+			Price:        6,
+			Duration:     90,
+			Issued:       testIssued,
+		}},
+		expectOrderChanges(t, c, "deletion"),
+	)
+}
+
+func TestEndToEndContracts(t *testing.T) {
+	const region = int32(10000002)
+	expires := time.Now().UTC().Add(7 * 24 * time.Hour).Truncate(time.Second)
+
+	exchange := esi.GetContractsPublicRegionId200Ok{
+		ContractId:      100,
+		Type_:           "item_exchange",
+		Price:           1000000,
+		StartLocationId: 60003760,
+		EndLocationId:   60003760,
+		DateIssued:      testIssued,
+		DateExpired:     expires,
+		IssuerId:        90000001,
+		Title:           "three things",
+		Volume:          3,
+	}
+	auction := esi.GetContractsPublicRegionId200Ok{
+		ContractId:      200,
+		Type_:           "auction",
+		Price:           5000,
+		Buyout:          100000,
+		StartLocationId: 60003760,
+		EndLocationId:   60003760,
+		DateIssued:      testIssued,
+		DateExpired:     expires,
+		IssuerId:        90000002,
+		Volume:          1,
+	}
+	items := []esi.GetContractsPublicItemsContractId200Ok{
+		{RecordId: 1, TypeId: 34, Quantity: 1000, IsIncluded: true},
+		{RecordId: 2, TypeId: 35, Quantity: 500, IsIncluded: true},
+		{RecordId: 3, TypeId: 36, Quantity: 250, IsIncluded: true},
+	}
+	lot := []esi.GetContractsPublicItemsContractId200Ok{
+		{RecordId: 4, TypeId: 587, Quantity: 1, IsIncluded: true, ItemId: 1000000001},
+	}
+	bids := []esi.GetContractsPublicBidsContractId200Ok{
+		{BidId: 1, Amount: 5000, DateBid: testIssued},
+		{BidId: 2, Amount: 6000, DateBid: testIssued.Add(time.Hour)},
+	}
+
+	f := newFakeESI()
+	defer f.Close()
+	f.setRegions(region)
+	f.setContracts(region, exchange, auction)
+	f.setItems(100, items...)
+	f.setItems(200, lot...)
+	f.setBids(200, bids[0])
+
+	config := DefaultConfig()
+	config.Scope.DisableMarkets = true
+	c := runMarketWatch(t, f, config, "contract=1")
+
+	contracts := []FullContract{}
+	expectFrame(t, c, "contractAddition", &contracts)
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].Contract.ContractId < contracts[j].Contract.ContractId })
+	for _, contract := range contracts {
+		sort.Slice(contract.Items, func(i, j int) bool { return contract.Items[i].RecordId < contract.Items[j].RecordId })
+	}
+	assert.Equal(t,
+		[]FullContract{
+			{Contract: exchange, Items: items},
+			{Contract: auction, Items: lot, Bids: bids[:1]},
+		},
+		contracts,
+	)
+
+	// A new bid
+	f.setBids(200, bids...)
+	changes := []ContractChange{}
+	expectFrame(t, c, "contractChange", &changes)
+	for i := range changes {
+		changes[i].TimeChanged = time.Time{}
+	}
+	assert.Equal(t,
+		[]ContractChange{{
+			ContractId:  200,
+			LocationId:  60003760,
+			DateExpired: expires,
+			Bids:        bids,
+			Price:       5000,
+			Type_:       "auction",
+		}},
+		changes,
+	)
+
+	// The exchange is accepted
+	f.setContracts(region, auction)
+	changes = []ContractChange{}
+	expectFrame(t, c, "contractDeletion", &changes)
+	for i := range changes {
+		changes[i].TimeChanged = time.Time{}
+	}
+	assert.Equal(t,
+		[]ContractChange{{
+			ContractId:  100,
+			LocationId:  60003760,
+			DateExpired: expires,
+			Price:       1000000,
+			Type_:       "item_exchange",
+		}},
+		changes,
+	)
+}
+
+func TestEndToEndStructures(t *testing.T) {
+	const (
+		structure = int64(1000000000001)
+		forbidden = int64(1000000000002)
+	)
+	order := func(orderID int64, volume int32) esi.GetMarketsStructuresStructureId200Ok {
+		return esi.GetMarketsStructuresStructureId200Ok{
+			OrderId:      orderID,
+			LocationId:   structure,
+			TypeId:       34,
+			Duration:     30,
+			Issued:       testIssued,
+			MinVolume:    1,
+			Range_:       "station",
+			IsBuyOrder:   true,
+			Price:        4.5,
+			VolumeRemain: volume,
+			VolumeTotal:  1000,
+		}
+	}
+
+	f := newFakeESI()
+	defer f.Close()
+	f.setRegions()
+	f.setStructures(structure, forbidden)
+	f.setStructureOrders(structure, order(10, 1000))
+	f.fail(fmt.Sprintf("/v1/markets/structures/%d/", forbidden), 1, http.StatusForbidden)
+
+	config := DefaultConfig()
+	config.ClientID = "client"
+	config.Secret = "secret"
+	config.RefreshToken = "refresh"
+	config.Scope.DisableContracts = true
+	config.Websocket.Keys = []wsbroadcast.APIKey{
+		{Name: "public", Key: "public-key"},
+		{Name: "trusted", Key: "trusted-key", Private: true},
+	}
+	mw := newTestMarketWatch(t, f, config)
+	startMarketWatch(t, mw)
+	c := dialMarketWatch(t, mw, "market=1&key=trusted-key")
+
+	assert.Equal(t,
+		[]esi.GetMarketsRegionIdOrders200Ok{sToR(order(10, 1000))},
+		expectOrders(t, c, "addition"),
+	)
+
+	// Only trusted keys see structure markets over the API
+	mux := http.NewServeMux()
+	mw.registerAPI(mux)
+	api := httptest.NewServer(mux)
+	defer api.Close()
+	get := func(key string) (int, []esi.GetMarketsRegionIdOrders200Ok) {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/orders?location=%d", api.URL, structure), nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer resp.Body.Close()
+		orders := []esi.GetMarketsRegionIdOrders200Ok{}
+		if resp.StatusCode == http.StatusOK {
+			assert.Nil(t, json.NewDecoder(resp.Body).Decode(&orders))
+		}
+		return resp.StatusCode, orders
+	}
+	status, orders := get("")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, orders = get("public-key")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, orders)
+	status, orders = get("trusted-key")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, orders, 1)
+
+	f.setStructureOrders(structure, order(10, 400))
+	assert.Equal(t,
+		[]OrderChange{{
+			OrderID:      10,
+			LocationId:   structure,
+			TypeID:       34,
+			VolumeChange: 600,
+			VolumeRemain: 400,
+			Price:        4.5,
+			Duration:     30,
+			IsBuyOrder:   true,
+			Issued:       testIssued,
+		}},
+		expectOrderChanges(t, c, "change"),
+	)
+
+	// No access, so it is left alone
+	for f.failing() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 1, f.served(fmt.Sprintf("/v1/markets/structures/%d/", forbidden), 1))
+}
+
+// recordingTransport notes the user agents it sees
+type recordingTransport struct {
+	mutex  sync.Mutex
+	agents []string
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mutex.Lock()
+	r.agents = append(r.agents, req.Header.Get("User-Agent"))
+	r.mutex.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestESIOptions(t *testing.T) {
+	f := newFakeESI()
+	defer f.Close()
+	f.setRegions(10000002)
+
+	transport := &recordingTransport{}
+	config := DefaultConfig()
+	config.ESI.URL = f.URL
+	config.ESI.UserAgent = "staging-mirror"
+	config.ESI.Transport = transport
+	mw, err := NewMarketWatch(config)
+	assert.Nil(t, err)
+
+	regions, _, err := mw.esi.ESI.UniverseApi.GetUniverseRegions(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{10000002}, regions)
+	assert.Equal(t, []string{"staging-mirror"}, transport.agents)
+
+	config.ESI.HTTPClient = http.DefaultClient
+	assert.NotNil(t, config.Validate())
+}
+
+// nextEvent reads the next event, failing the test if none arrives in time
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(10 * time.Second):
+		t.Fatal("no event received")
+	}
+	return nil
+}
+
+func TestSubscribe(t *testing.T) {
+	const region = int32(10000002)
+
+	f := newFakeESI()
+	defer f.Close()
+	f.setRegions(region)
+	tritanium := testOrder(1, 100, 5)
+	pyerite := testOrder(2, 100, 10)
+	pyerite.TypeId = 35
+	f.setOrders(region, tritanium, pyerite)
+
+	config := DefaultConfig()
+	config.Scope.DisableContracts = true
+	mw := newTestMarketWatch(t, f, config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	filter, err := wsbroadcast.ParseFilter(url.Values{"type": {"35"}})
+	assert.Nil(t, err)
+	events := mw.Subscribe(ctx, filter)
+	startMarketWatch(t, mw)
+
+	e := nextEvent(t, events)
+	if assert.IsType(t, OrderAdded{}, e) {
+		added := e.(OrderAdded)
+		assert.Equal(t, int64(region), added.RegionID)
+		assert.Equal(t, []esi.GetMarketsRegionIdOrders200Ok{pyerite}, added.Orders)
+		assert.False(t, added.PolledAt.IsZero())
+	}
+	assert.IsType(t, BookChanged{}, nextEvent(t, events))
+	assert.Len(t, mw.Snapshot().Markets[int64(region)], 2)
+
+	// Both fill, only pyerite comes through
+	tritanium.VolumeRemain = 50
+	pyerite.VolumeRemain = 60
+	f.setOrders(region, tritanium, pyerite)
+
+	e = nextEvent(t, events)
+	if assert.IsType(t, OrderChanged{}, e) {
+		changes := e.(OrderChanged).Changes
+		assert.Len(t, changes, 1)
+		assert.Equal(t, int64(2), changes[0].OrderID)
+		assert.Equal(t, int32(40), changes[0].VolumeChange)
+	}
+	assert.IsType(t, BookChanged{}, nextEvent(t, events))
+	e = nextEvent(t, events)
+	if assert.IsType(t, TradeInferred{}, e) {
+		trades := e.(TradeInferred).Trades
+		assert.Len(t, trades, 1)
+		assert.Equal(t, int32(35), trades[0].TypeID)
+		assert.Equal(t, "buy", trades[0].Side)
+	}
+
+	// Closed when cancelled
+	cancel()
+	for range events {
+	}
+}
+
+func TestSubscribeSlow(t *testing.T) {
+	mw, err := NewMarketWatch(DefaultConfig())
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slow := mw.Subscribe(ctx, nil)
+	fast := mw.Subscribe(ctx, nil)
+
+	// A subscriber that stops reading is dropped without holding up the others
+	for i := 0; i <= subscriberBuffer; i++ {
+		mw.publish(OrderAdded{RegionID: 10000002, Orders: []esi.GetMarketsRegionIdOrders200Ok{testOrder(int64(i), 100, 5)}})
+		assert.IsType(t, OrderAdded{}, nextEvent(t, fast))
+	}
+	n := 0
+	for range slow {
+		n++
+	}
+	assert.Equal(t, subscriberBuffer, n)
+	assert.Nil(t, ctx.Err())
+}
+
+// nextBinary reads the next message, which must be a binary frame
+func nextBinary(t *testing.T, c *websocket.Conn) []byte {
+	c.SetReadDeadline(time.Now().Add(10 * time.Second))
+	kind, b, err := c.ReadMessage()
+	if err != nil {
+		t.Fatalf("no message received: %v", err)
+	}
+	assert.Equal(t, websocket.BinaryMessage, kind)
+	return b
+}
+
+func TestEncodings(t *testing.T) {
+	const region = int32(10000002)
+
+	f := newFakeESI()
+	defer f.Close()
+	f.setRegions(region)
+	f.setOrders(region, testOrder(1, 100, 5))
+
+	config := DefaultConfig()
+	config.Scope.DisableContracts = true
+	mw := newTestMarketWatch(t, f, config)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := mw.Subscribe(ctx, nil)
+	startMarketWatch(t, mw)
+
+	// Wait for the first poll so it is in the dump
+	assert.IsType(t, OrderAdded{}, nextEvent(t, events))
+	cancel()
+
+	t.Run("protobuf", func(t *testing.T) {
+		c := dialMarketWatch(t, mw, "market=1&encoding=protobuf")
+
+		msg := &schema.Frame{}
+		assert.Nil(t, proto.Unmarshal(nextBinary(t, c), msg))
+		assert.Equal(t, "snapshotBegin", msg.Action)
+		assert.Equal(t, []string{"market"}, msg.GetSnapshot().GetChannels())
+
+		msg = &schema.Frame{}
+		assert.Nil(t, proto.Unmarshal(nextBinary(t, c), msg))
+		assert.Equal(t, uint32(SchemaVersion), msg.SchemaVersion)
+		assert.Equal(t, "addition", msg.Action)
+		assert.Equal(t, int64(region), msg.RegionId)
+		assert.NotNil(t, msg.Time)
+		if assert.Len(t, msg.GetOrders().GetItems(), 1) {
+			o := msg.GetOrders().GetItems()[0]
+			assert.Equal(t, int64(1), o.OrderId)
+			assert.Equal(t, int32(100), o.VolumeRemain)
+			assert.Equal(t, "region", o.Range)
+			assert.Equal(t, testIssued, o.Issued.AsTime())
+		}
+
+		msg = &schema.Frame{}
+		assert.Nil(t, proto.Unmarshal(nextBinary(t, c), msg))
+		assert.Equal(t, "snapshotEnd", msg.Action)
+		assert.Equal(t, int64(1), msg.GetSnapshot().GetItems())
+
+		// Replies to commands are frames too
+		assert.Nil(t, c.WriteJSON(wsbroadcast.Command{ID: "1", Action: "ping"}))
+		msg = &schema.Frame{}
+		assert.Nil(t, proto.Unmarshal(nextBinary(t, c), msg))
+		assert.Equal(t, "pong", msg.Action)
+		assert.Equal(t, "1", msg.GetAck().GetId())
+		assert.Equal(t, []string{"market"}, msg.GetAck().GetChannels())
+	})
+
+	t.Run("msgpack", func(t *testing.T) {
+		c := dialMarketWatch(t, mw, "market=1", "msgpack")
+		assert.Equal(t, "msgpack", c.Subprotocol())
+
+		marker := struct {
+			Action string `msgpack:"action"`
+		}{}
+		assert.Nil(t, msgpack.Unmarshal(nextBinary(t, c), &marker))
+		assert.Equal(t, "snapshotBegin", marker.Action)
+
+		msg := struct {
+			SchemaVersion int       `msgpack:"schema_version"`
+			Action        string    `msgpack:"action"`
+			RegionID      int64     `msgpack:"region_id"`
+			Time          time.Time `msgpack:"time"`
+			Payload       []struct {
+				OrderID int64     `msgpack:"order_id"`
+				Price   float64   `msgpack:"price"`
+				Issued  time.Time `msgpack:"issued"`
+			} `msgpack:"payload"`
+		}{}
+		assert.Nil(t, msgpack.Unmarshal(nextBinary(t, c), &msg))
+		assert.Equal(t, SchemaVersion, msg.SchemaVersion)
+		assert.Equal(t, "addition", msg.Action)
+		assert.Equal(t, int64(region), msg.RegionID)
+		assert.False(t, msg.Time.IsZero())
+		if assert.Len(t, msg.Payload, 1) {
+			assert.Equal(t, int64(1), msg.Payload[0].OrderID)
+			assert.Equal(t, float64(5), msg.Payload[0].Price)
+			assert.True(t, testIssued.Equal(msg.Payload[0].Issued))
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(mw.broadcast.ServeWs))
+		defer server.Close()
+		u := url.URL{Scheme: "ws", Host: server.Listener.Addr().String(), Path: "/", RawQuery: "encoding=xml"}
+		_, res, err := websocket.DefaultDialer.Dial(u.String(), nil)
+		assert.NotNil(t, err)
+		if assert.NotNil(t, res) {
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		}
+	})
+}
+
+func TestSnapshotChunks(t *testing.T) {
+	const region = int32(10000002)
+
+	f := newFakeESI()
+	defer f.Close()
+	f.setRegions(region)
+	f.setOrders(region, testOrder(1, 100, 5), testOrder(2, 100, 5), testOrder(3, 100, 5), testOrder(4, 100, 5), testOrder(5, 100, 5))
+
+	config := DefaultConfig()
+	config.Scope.DisableContracts = true
+	config.Websocket.SnapshotChunk = 2
+	mw := newTestMarketWatch(t, f, config)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := mw.Subscribe(ctx, nil)
+	startMarketWatch(t, mw)
+
+	// Wait for the first poll so it is in the dump
+	assert.IsType(t, OrderAdded{}, nextEvent(t, events))
+	cancel()
+
+	c := dialMarketWatch(t, mw, "market=1")
+	begin := wsbroadcast.Snapshot{}
+	expectFrame(t, c, "snapshotBegin", &begin)
+	assert.Equal(t, []string{"market"}, begin.Channels)
+
+	// Chunks of at most two orders
+	orders := []esi.GetMarketsRegionIdOrders200Ok{}
+	for _, size := range []int{2, 2, 1} {
+		chunk := expectOrders(t, c, "addition")
+		assert.Len(t, chunk, size)
+		orders = append(orders, chunk...)
+	}
+	assert.Len(t, orders, 5)
+
+	end := wsbroadcast.Snapshot{}
+	expectFrame(t, c, "snapshotEnd", &end)
+	assert.Equal(t, wsbroadcast.Snapshot{Stream: begin.Stream, Sequence: begin.Sequence, Channels: []string{"market"}, Messages: 3, Items: 5}, end)
+
+	// Skipping the snapshot goes straight to live messages
+	c = dialMarketWatch(t, mw, "market=1&snapshot=0")
+	assert.Nil(t, c.WriteJSON(wsbroadcast.Command{Action: "ping"}))
+	expectFrame(t, c, "pong", &wsbroadcast.Ack{})
+}
@@ -0,0 +1,76 @@
+package marketwatch
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ESIOptions control where ESI and SSO are reached and how.
+// Everything is optional and defaults to the public tranquility endpoints.
+type ESIOptions struct {
+	// ESI host to poll, such as a caching proxy or a Singularity mirror
+	URL string `yaml:"url"`
+
+	// User agent sent with every request
+	UserAgent string `yaml:"user_agent"`
+
+	// SSO endpoints used to refresh the structure token
+	SSOAuthURL  string `yaml:"sso_auth_url"`
+	SSOTokenURL string `yaml:"sso_token_url"`
+
+	// HTTPClient is used as is, skipping the concurrency limit, error backoff and metrics.
+	HTTPClient *http.Client `yaml:"-"`
+
+	// Transport replaces the default connection pool underneath the limit, backoff and metrics.
+	Transport http.RoundTripper `yaml:"-"`
+}
+
+const defaultUserAgent = "eve-marketwatch"
+
+// Validate checks the options do not conflict
+func (o *ESIOptions) Validate() error {
+	if o.HTTPClient != nil && o.Transport != nil {
+		return errors.New("esi: set either an HTTP client or a transport, not both")
+	}
+	return nil
+}
+
+// httpClient builds the client ESI and SSO requests go through
+func (o *ESIOptions) httpClient(concurrentRequests int) *http.Client {
+	if o.HTTPClient != nil {
+		return o.HTTPClient
+	}
+
+	next := o.Transport
+	if next == nil {
+		next = &http.Transport{
+			MaxIdleConns: 200,
+			DialContext: (&net.Dialer{
+				Timeout:   300 * time.Second,
+				KeepAlive: 5 * 60 * time.Second,
+				DualStack: true,
+			}).DialContext,
+			IdleConnTimeout:       5 * 60 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 60 * time.Second,
+			ExpectContinueTimeout: 0,
+			MaxIdleConnsPerHost:   20,
+		}
+	}
+
+	return &http.Client{
+		Transport: &ApiTransport{
+			next:    next,
+			limiter: make(chan bool, concurrentRequests),
+		},
+	}
+}
+
+func (o *ESIOptions) userAgent() string {
+	if o.UserAgent == "" {
+		return defaultUserAgent
+	}
+	return o.UserAgent
+}
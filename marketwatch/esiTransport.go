@@ -10,28 +10,31 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-var apiTransportLimiter chan bool
 var urlFilterRe *regexp.Regexp
 
 func init() {
-	// concurrency limiter
-	// 100 concurrent requests should fill 1 connection
-	apiTransportLimiter = make(chan bool, 100)
 	urlFilterRe = regexp.MustCompile("/v[0-9]{1}/|/[0-9]+/")
 }
 
 // ApiTransport custom transport to chain into the HTTPClient to gather statistics.
 type ApiTransport struct {
-	next *http.Transport
+	next http.RoundTripper
+
+	// concurrency limiter
+	limiter chan bool
 }
 
 // RoundTrip wraps http.DefaultTransport.RoundTrip to provide stats and handle error rates.
 func (t *ApiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	// Limit concurrency
-	apiTransportLimiter <- true
+	select {
+	case t.limiter <- true:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
 
 	// Free the worker
-	defer func() { <-apiTransportLimiter }() // Loop until success
+	defer func() { <-t.limiter }() // Loop until success
 
 	tries := 0
 	for {
@@ -77,17 +80,17 @@ func (t *ApiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 				metricAPIErrors.Inc()
 				log.Printf("St: %d Res: %s Tok: %s - %s\n", res.StatusCode, resetS, tokensS, req.URL)
 				if !esiRateLimiter { // Not an ESI error
-					time.Sleep(time.Second * time.Duration(tries))
+					sleep(req.Context(), time.Second*time.Duration(tries))
 				}
 			}
 
 			// Backoff
 			if res.StatusCode == 420 { // Something went wrong
-				time.Sleep(time.Duration(reset) * time.Second)
+				sleep(req.Context(), time.Duration(reset)*time.Second)
 			} else if esiRateLimiter { // Sleep based on error rate.
 				percentRemain := 1 - (tokens / 100)
 				duration := reset * percentRemain
-				time.Sleep(time.Second * time.Duration(duration))
+				sleep(req.Context(), time.Second*time.Duration(duration))
 			}
 
 			// Get out for "our bad" statuses
@@ -102,6 +105,14 @@ func (t *ApiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 			}
 		}
 
+		// Stop retrying once the request is cancelled
+		if err := req.Context().Err(); err != nil {
+			if res != nil {
+				res.Body.Close()
+			}
+			return nil, err
+		}
+
 		if tries > 10 {
 			log.Printf("Too many tries\n")
 			return res, triperr
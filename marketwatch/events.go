@@ -0,0 +1,210 @@
+package marketwatch
+
+import (
+	"time"
+
+	"github.com/antihax/eve-marketwatch/wsbroadcast"
+	"github.com/antihax/goesi/esi"
+)
+
+// Websocket channels and the actions of the events sent on them
+var eventActions = map[string][]string{
+	"market":   {"addition", "change", "deletion"},
+	"contract": {"contractAddition", "contractChange", "contractDeletion"},
+	"book":     {"bookChange"},
+	"trades":   {"trade"},
+	"candles":  {"candle"},
+}
+
+// Event is a change found by a poll, one of the types below.
+// Each event carries everything one poll of a region or structure found.
+type Event interface {
+	// Action names the event the same as the websocket stream
+	Action() string
+
+	// Region the event happened in, zero for structure markets
+	Region() int64
+
+	// PollTime is when the poll that found the event started
+	PollTime() time.Time
+
+	// websocket channel the event goes out on
+	channel() string
+
+	// the batch of orders, contracts, tops, trades or candles
+	payload() interface{}
+	withPayload(interface{}) Event
+}
+
+// OrderAdded is new orders
+type OrderAdded struct {
+	RegionID int64
+	Orders   []esi.GetMarketsRegionIdOrders200Ok
+	PolledAt time.Time
+}
+
+// OrderChanged is orders whose volume, price or duration changed
+type OrderChanged struct {
+	RegionID int64
+	Changes  []OrderChange
+	PolledAt time.Time
+}
+
+// OrderDeleted is orders that were filled, cancelled or expired
+type OrderDeleted struct {
+	RegionID  int64
+	Deletions []OrderChange
+	PolledAt  time.Time
+}
+
+// ContractAdded is new contracts with their items and bids
+type ContractAdded struct {
+	RegionID  int64
+	Contracts []FullContract
+	PolledAt  time.Time
+}
+
+// ContractChanged is auctions that received bids
+type ContractChanged struct {
+	RegionID int64
+	Changes  []ContractChange
+	PolledAt time.Time
+}
+
+// ContractDeleted is contracts that were accepted or expired
+type ContractDeleted struct {
+	RegionID  int64
+	Deletions []ContractChange
+	PolledAt  time.Time
+}
+
+// BookChanged is tops of book that moved
+type BookChanged struct {
+	RegionID int64
+	Tops     []BookTop
+	PolledAt time.Time
+}
+
+// TradeInferred is trades inferred from order changes
+type TradeInferred struct {
+	RegionID int64
+	Trades   []Trade
+	PolledAt time.Time
+}
+
+// CandleClosed is candles whose interval passed
+type CandleClosed struct {
+	RegionID int64
+	Candles  []Candle
+	PolledAt time.Time
+}
+
+func (e OrderAdded) Action() string       { return "addition" }
+func (e OrderAdded) Region() int64        { return e.RegionID }
+func (e OrderAdded) PollTime() time.Time  { return e.PolledAt }
+func (e OrderAdded) channel() string      { return "market" }
+func (e OrderAdded) payload() interface{} { return e.Orders }
+func (e OrderAdded) withPayload(p interface{}) Event {
+	e.Orders = p.([]esi.GetMarketsRegionIdOrders200Ok)
+	return e
+}
+
+func (e OrderChanged) Action() string       { return "change" }
+func (e OrderChanged) Region() int64        { return e.RegionID }
+func (e OrderChanged) PollTime() time.Time  { return e.PolledAt }
+func (e OrderChanged) channel() string      { return "market" }
+func (e OrderChanged) payload() interface{} { return e.Changes }
+func (e OrderChanged) withPayload(p interface{}) Event {
+	e.Changes = p.([]OrderChange)
+	return e
+}
+
+func (e OrderDeleted) Action() string       { return "deletion" }
+func (e OrderDeleted) Region() int64        { return e.RegionID }
+func (e OrderDeleted) PollTime() time.Time  { return e.PolledAt }
+func (e OrderDeleted) channel() string      { return "market" }
+func (e OrderDeleted) payload() interface{} { return e.Deletions }
+func (e OrderDeleted) withPayload(p interface{}) Event {
+	e.Deletions = p.([]OrderChange)
+	return e
+}
+
+func (e ContractAdded) Action() string       { return "contractAddition" }
+func (e ContractAdded) Region() int64        { return e.RegionID }
+func (e ContractAdded) PollTime() time.Time  { return e.PolledAt }
+func (e ContractAdded) channel() string      { return "contract" }
+func (e ContractAdded) payload() interface{} { return e.Contracts }
+func (e ContractAdded) withPayload(p interface{}) Event {
+	e.Contracts = p.([]FullContract)
+	return e
+}
+
+func (e ContractChanged) Action() string       { return "contractChange" }
+func (e ContractChanged) Region() int64        { return e.RegionID }
+func (e ContractChanged) PollTime() time.Time  { return e.PolledAt }
+func (e ContractChanged) channel() string      { return "contract" }
+func (e ContractChanged) payload() interface{} { return e.Changes }
+func (e ContractChanged) withPayload(p interface{}) Event {
+	e.Changes = p.([]ContractChange)
+	return e
+}
+
+func (e ContractDeleted) Action() string       { return "contractDeletion" }
+func (e ContractDeleted) Region() int64        { return e.RegionID }
+func (e ContractDeleted) PollTime() time.Time  { return e.PolledAt }
+func (e ContractDeleted) channel() string      { return "contract" }
+func (e ContractDeleted) payload() interface{} { return e.Deletions }
+func (e ContractDeleted) withPayload(p interface{}) Event {
+	e.Deletions = p.([]ContractChange)
+	return e
+}
+
+func (e BookChanged) Action() string       { return "bookChange" }
+func (e BookChanged) Region() int64        { return e.RegionID }
+func (e BookChanged) PollTime() time.Time  { return e.PolledAt }
+func (e BookChanged) channel() string      { return "book" }
+func (e BookChanged) payload() interface{} { return e.Tops }
+func (e BookChanged) withPayload(p interface{}) Event {
+	e.Tops = p.([]BookTop)
+	return e
+}
+
+func (e TradeInferred) Action() string       { return "trade" }
+func (e TradeInferred) Region() int64        { return e.RegionID }
+func (e TradeInferred) PollTime() time.Time  { return e.PolledAt }
+func (e TradeInferred) channel() string      { return "trades" }
+func (e TradeInferred) payload() interface{} { return e.Trades }
+func (e TradeInferred) withPayload(p interface{}) Event {
+	e.Trades = p.([]Trade)
+	return e
+}
+
+func (e CandleClosed) Action() string       { return "candle" }
+func (e CandleClosed) Region() int64        { return e.RegionID }
+func (e CandleClosed) PollTime() time.Time  { return e.PolledAt }
+func (e CandleClosed) channel() string      { return "candles" }
+func (e CandleClosed) payload() interface{} { return e.Candles }
+func (e CandleClosed) withPayload(p interface{}) Event {
+	e.Candles = p.([]Candle)
+	return e
+}
+
+// filterEvent reduces an event to what the filter allows
+func filterEvent(e Event, f *wsbroadcast.Filter) (Event, bool) {
+	if f.Empty() {
+		return e, true
+	}
+	if !f.MatchRegion(e.Region()) {
+		return nil, false
+	}
+	p, ok := filterPayload(f, e.payload())
+	if !ok {
+		return nil, false
+	}
+	return e.withPayload(p), true
+}
+
+// eventMessage wraps an event for the websocket stream
+func eventMessage(e Event) Message {
+	return newMessage(e.Action(), e.Region(), e.PollTime(), e.payload())
+}
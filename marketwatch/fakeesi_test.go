@@ -0,0 +1,199 @@
+package marketwatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antihax/goesi/esi"
+)
+
+// fakeESI serves scripted ESI responses with the paging, cache and error limit
+// headers the workers rely on. Each endpoint serves whatever was last set for it,
+// pinned from the first page so a poll never sees pages from two versions.
+type fakeESI struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	pageSize int
+	data     map[string][]interface{}
+	pinned   map[string][]interface{}
+	failures map[string][]int
+	requests map[string]int
+}
+
+func newFakeESI() *fakeESI {
+	f := &fakeESI{
+		pageSize: 2,
+		data:     make(map[string][]interface{}),
+		pinned:   make(map[string][]interface{}),
+		failures: make(map[string][]int),
+		requests: make(map[string]int),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+func (f *fakeESI) set(path string, list []interface{}) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.data[path] = list
+}
+
+func (f *fakeESI) setRegions(regions ...int32) {
+	list := []interface{}{}
+	for _, r := range regions {
+		list = append(list, r)
+	}
+	f.set("/v1/universe/regions/", list)
+}
+
+func (f *fakeESI) setStructures(structures ...int64) {
+	list := []interface{}{}
+	for _, s := range structures {
+		list = append(list, s)
+	}
+	f.set("/v1/universe/structures/", list)
+}
+
+func (f *fakeESI) setOrders(regionID int32, orders ...esi.GetMarketsRegionIdOrders200Ok) {
+	list := []interface{}{}
+	for _, o := range orders {
+		list = append(list, o)
+	}
+	f.set(fmt.Sprintf("/v1/markets/%d/orders/", regionID), list)
+}
+
+func (f *fakeESI) setStructureOrders(structureID int64, orders ...esi.GetMarketsStructuresStructureId200Ok) {
+	list := []interface{}{}
+	for _, o := range orders {
+		list = append(list, o)
+	}
+	f.set(fmt.Sprintf("/v1/markets/structures/%d/", structureID), list)
+}
+
+func (f *fakeESI) setContracts(regionID int32, contracts ...esi.GetContractsPublicRegionId200Ok) {
+	list := []interface{}{}
+	for _, c := range contracts {
+		list = append(list, c)
+	}
+	f.set(fmt.Sprintf("/v1/contracts/public/%d/", regionID), list)
+}
+
+func (f *fakeESI) setItems(contractID int32, items ...esi.GetContractsPublicItemsContractId200Ok) {
+	list := []interface{}{}
+	for _, i := range items {
+		list = append(list, i)
+	}
+	f.set(fmt.Sprintf("/v1/contracts/public/items/%d/", contractID), list)
+}
+
+func (f *fakeESI) setBids(contractID int32, bids ...esi.GetContractsPublicBidsContractId200Ok) {
+	list := []interface{}{}
+	for _, b := range bids {
+		list = append(list, b)
+	}
+	f.set(fmt.Sprintf("/v1/contracts/public/bids/%d/", contractID), list)
+}
+
+// fail the next requests for a page with these statuses, in order
+func (f *fakeESI) fail(path string, page int, statuses ...int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	key := path + "?" + strconv.Itoa(page)
+	f.failures[key] = append(f.failures[key], statuses...)
+}
+
+// failing reports if any scripted failures have not been served yet
+func (f *fakeESI) failing() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, statuses := range f.failures {
+		if len(statuses) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// served counts the requests made for a page
+func (f *fakeESI) served(path string, page int) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.requests[path+"?"+strconv.Itoa(page)]
+}
+
+func (f *fakeESI) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v2/oauth/token" {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"access","token_type":"Bearer","expires_in":1200,"refresh_token":"refresh"}`)
+		return
+	}
+
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		page, _ = strconv.Atoi(p)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	key := r.URL.Path + "?" + strconv.Itoa(page)
+	f.requests[key]++
+
+	// Always expired so workers poll again straight away
+	w.Header().Set("Expires", time.Now().UTC().Format(http.TimeFormat))
+	w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+	w.Header().Set("Content-Type", "application/json")
+
+	if statuses := f.failures[key]; len(statuses) > 0 {
+		f.failures[key] = statuses[1:]
+		w.Header().Set("x-esi-error-limit-remain", "99")
+		w.Header().Set("x-esi-error-limit-reset", "1")
+		w.WriteHeader(statuses[0])
+		fmt.Fprintf(w, `{"error":"%s"}`, http.StatusText(statuses[0]))
+		return
+	}
+	w.Header().Set("x-esi-error-limit-remain", "100")
+	w.Header().Set("x-esi-error-limit-reset", "60")
+
+	if page == 1 {
+		data, ok := f.data[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"Not found"}`)
+			return
+		}
+		f.pinned[r.URL.Path] = data
+	}
+	list := f.pinned[r.URL.Path]
+
+	// Universe lists are not paged
+	if strings.HasPrefix(r.URL.Path, "/v1/universe/") {
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	pages := (len(list) + f.pageSize - 1) / f.pageSize
+	if pages == 0 {
+		pages = 1
+	}
+	if page > pages {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"Requested page does not exist!"}`)
+		return
+	}
+	w.Header().Set("x-pages", strconv.Itoa(pages))
+
+	start := (page - 1) * f.pageSize
+	end := start + f.pageSize
+	if end > len(list) {
+		end = len(list)
+	}
+	json.NewEncoder(w).Encode(append([]interface{}{}, list[start:end]...))
+}
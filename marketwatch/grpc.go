@@ -0,0 +1,236 @@
+package marketwatch
+
+import (
+	"context"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/antihax/eve-marketwatch/schema"
+	"github.com/antihax/eve-marketwatch/wsbroadcast"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcServer serves the MarketWatch gRPC service from the same state and
+// events as the websocket
+type grpcServer struct {
+	schema.UnimplementedMarketWatchServer
+	s *MarketWatch
+
+	// closed when the service stops, ending the streams
+	done <-chan struct{}
+}
+
+// newGRPCServer builds the gRPC server, requiring the websocket API keys when any are configured
+func (s *MarketWatch) newGRPCServer(done <-chan struct{}) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(s.grpcUnaryAuth),
+		grpc.StreamInterceptor(s.grpcStreamAuth),
+	)
+	schema.RegisterMarketWatchServer(server, &grpcServer{s: s, done: done})
+	return server
+}
+
+// serveGRPC listens on the configured address until the server is stopped
+func (s *MarketWatch) serveGRPC(server *grpc.Server) error {
+	lis, err := net.Listen("tcp", s.config.GRPCAddress)
+	if err != nil {
+		return err
+	}
+	return server.Serve(lis)
+}
+
+// grpcKey adds the API key sent in the metadata to the context
+func (s *MarketWatch) grpcKey(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	sent := ""
+	if v := md.Get("authorization"); len(v) > 0 && strings.HasPrefix(v[0], "Bearer ") {
+		sent = strings.TrimPrefix(v[0], "Bearer ")
+	} else if v := md.Get("x-api-key"); len(v) > 0 {
+		sent = v[0]
+	}
+	key, err := s.broadcast.LookupKey(sent)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return context.WithValue(ctx, apiKey{}, key), nil
+}
+
+func (s *MarketWatch) grpcUnaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.grpcKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *MarketWatch) grpcStreamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.grpcKey(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &keyStream{ServerStream: ss, ctx: ctx})
+}
+
+// keyStream carries the API key in the stream context
+type keyStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (k *keyStream) Context() context.Context { return k.ctx }
+
+// StreamOrders sends the current orders then the changes to them
+func (g *grpcServer) StreamOrders(req *schema.StreamRequest, stream grpc.ServerStreamingServer[schema.Frame]) error {
+	return g.stream(req, "market", stream)
+}
+
+// StreamContracts sends the current contracts then the changes to them
+func (g *grpcServer) StreamContracts(req *schema.StreamRequest, stream grpc.ServerStreamingServer[schema.Frame]) error {
+	return g.stream(req, "contract", stream)
+}
+
+// stream sends the current state of a channel then the events on it, until the
+// client goes away or the service stops. Polling never waits on a stream, one
+// that falls too far behind is ended like a slow websocket client.
+func (g *grpcServer) stream(req *schema.StreamRequest, channel string, stream grpc.ServerStreamingServer[schema.Frame]) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	if !requestKey(ctx).AllowChannel(channel) {
+		return status.Errorf(codes.PermissionDenied, "channel %q not allowed", channel)
+	}
+	filter := protoFilter(req.Filter)
+
+	// Subscribe before taking the snapshot so no change is missed in between
+	events := g.s.Subscribe(ctx, filter)
+	queue := make(chan Event, g.s.config.Websocket.SendBuffer)
+	lagging := make(chan struct{})
+	go func() {
+		defer close(queue)
+		for e := range events {
+			if e.channel() != channel || !allowRegion(ctx, e.Region()) {
+				continue
+			}
+			select {
+			case queue <- e:
+			default:
+				close(lagging)
+				cancel()
+				return
+			}
+		}
+		// The subscription was dropped for falling behind
+		if ctx.Err() == nil {
+			close(lagging)
+		}
+	}()
+
+	if !req.SkipSnapshot {
+		if err := g.snapshot(ctx, channel, filter, stream); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case e, ok := <-queue:
+			if !ok {
+				select {
+				case <-lagging:
+					return status.Error(codes.ResourceExhausted, "too far behind, reconnect")
+				default:
+					return status.FromContextError(ctx.Err()).Err()
+				}
+			}
+			frame, err := protoFrame(eventMessage(e))
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			if err := stream.Send(frame); err != nil {
+				return err
+			}
+		case <-g.done:
+			return status.Error(codes.Unavailable, "shutting down")
+		}
+	}
+}
+
+// snapshot sends the current state of a channel framed by snapshotBegin and snapshotEnd
+func (g *grpcServer) snapshot(ctx context.Context, channel string, filter *wsbroadcast.Filter, stream grpc.ServerStreamingServer[schema.Frame]) error {
+	messages := make(chan interface{})
+	go func() {
+		defer close(messages)
+		g.s.dumpMarket(map[string]bool{channel: true}, filter, messages)
+	}()
+
+	snapshot := wsbroadcast.Snapshot{Channels: []string{channel}}
+	err := g.sendMarker(stream, "snapshotBegin", snapshot)
+	for m := range messages {
+		// Let the dump finish once the stream has failed
+		if err != nil {
+			continue
+		}
+		msg := m.(Message)
+		if !allowRegion(ctx, msg.RegionID) {
+			continue
+		}
+		snapshot.Messages++
+		snapshot.Items += msg.Len()
+		var frame *schema.Frame
+		if frame, err = protoFrame(msg); err == nil {
+			err = stream.Send(frame)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return g.sendMarker(stream, "snapshotEnd", snapshot)
+}
+
+func (g *grpcServer) sendMarker(stream grpc.ServerStreamingServer[schema.Frame], action string, snapshot wsbroadcast.Snapshot) error {
+	frame, err := protoFrame(wsbroadcast.SnapshotMarker{Action: action, Payload: snapshot})
+	if err != nil {
+		return err
+	}
+	return stream.Send(frame)
+}
+
+// GetOrderBook returns the book for a type at a location
+func (g *grpcServer) GetOrderBook(ctx context.Context, req *schema.GetOrderBookRequest) (*schema.OrderBook, error) {
+	depth := defaultBookDepth
+	if req.Depth != nil {
+		depth = int(*req.Depth)
+	}
+	if depth < 0 || depth > maxBookDepth {
+		return nil, status.Errorf(codes.InvalidArgument, "depth must be between 0 and %d", maxBookDepth)
+	}
+
+	books := g.s.books.find(func(regionID, locationID int64, typeID int32) bool {
+		return locationID == req.LocationId && typeID == req.TypeId && allowRegion(ctx, regionID)
+	}, depth)
+	if len(books) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no orders for type %d at location %d", req.TypeId, req.LocationId)
+	}
+	return protoBook(&books[0]), nil
+}
+
+// GetContract returns a contract with its items and bids
+func (g *grpcServer) GetContract(ctx context.Context, req *schema.GetContractRequest) (*schema.FullContract, error) {
+	c, ok := g.s.findContract(req.ContractId)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "contract %d not found", req.ContractId)
+	}
+	return protoFullContract(&c.Contract), nil
+}
+
+// protoFilter converts a request filter, nil matches everything
+func protoFilter(f *schema.Filter) *wsbroadcast.Filter {
+	if f == nil {
+		return nil
+	}
+	return wsbroadcast.NewFilter(f.Region, f.Location, f.Type)
+}
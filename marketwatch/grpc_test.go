@@ -0,0 +1,173 @@
+package marketwatch
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/antihax/eve-marketwatch/schema"
+	"github.com/antihax/eve-marketwatch/wsbroadcast"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// dialGRPC serves the gRPC service in memory until the test ends
+func dialGRPC(t *testing.T, mw *MarketWatch, opts ...grpc.DialOption) schema.MarketWatchClient {
+	done := make(chan struct{})
+	lis := bufconn.Listen(1024 * 1024)
+	server := mw.newGRPCServer(done)
+	go server.Serve(lis)
+	t.Cleanup(func() {
+		close(done)
+		server.GracefulStop()
+	})
+
+	opts = append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { conn.Close() })
+	return schema.NewMarketWatchClient(conn)
+}
+
+// recvFrame reads the next frame from a stream
+func recvFrame(t *testing.T, stream grpc.ServerStreamingClient[schema.Frame]) *schema.Frame {
+	frame, err := stream.Recv()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return frame
+}
+
+func TestGRPC(t *testing.T) {
+	const region = int32(10000002)
+
+	f := newFakeESI()
+	defer f.Close()
+	f.setRegions(region)
+	f.setOrders(region, testOrder(1, 100, 5), testOrder(2, 50, 6))
+
+	config := DefaultConfig()
+	config.Scope.DisableContracts = true
+	config.Websocket.Keys = []wsbroadcast.APIKey{
+		{Name: "orders", Key: "orders-key", Channels: []string{"market"}},
+	}
+	mw := newTestMarketWatch(t, f, config)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := mw.Subscribe(ctx, nil)
+	startMarketWatch(t, mw)
+
+	// Wait for the first poll so it is in the snapshot
+	assert.IsType(t, OrderAdded{}, nextEvent(t, events))
+	assert.IsType(t, BookChanged{}, nextEvent(t, events))
+	cancel()
+
+	client := dialGRPC(t, mw)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// A key is required
+	_, err := client.GetContract(ctx, &schema.GetContractRequest{ContractId: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer orders-key")
+
+	stream, err := client.StreamContracts(ctx, &schema.StreamRequest{})
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// The snapshot, then the changes
+	stream, err = client.StreamOrders(ctx, &schema.StreamRequest{Filter: &schema.Filter{Type: []int64{34}}})
+	assert.Nil(t, err)
+	frame := recvFrame(t, stream)
+	assert.Equal(t, "snapshotBegin", frame.Action)
+	assert.Equal(t, []string{"market"}, frame.GetSnapshot().GetChannels())
+	frame = recvFrame(t, stream)
+	assert.Equal(t, "addition", frame.Action)
+	assert.Equal(t, int64(region), frame.RegionId)
+	assert.Len(t, frame.GetOrders().GetItems(), 2)
+	frame = recvFrame(t, stream)
+	assert.Equal(t, "snapshotEnd", frame.Action)
+	assert.Equal(t, int64(2), frame.GetSnapshot().GetItems())
+
+	f.setOrders(region, testOrder(1, 100, 5), testOrder(2, 20, 6))
+	frame = recvFrame(t, stream)
+	assert.Equal(t, "change", frame.Action)
+	if assert.Len(t, frame.GetOrderChanges().GetItems(), 1) {
+		c := frame.GetOrderChanges().GetItems()[0]
+		assert.Equal(t, int64(2), c.OrderId)
+		assert.Equal(t, int32(30), c.VolumeChange)
+		assert.Equal(t, int32(20), c.VolumeRemain)
+	}
+
+	// Queries of the current state
+	book, err := client.GetOrderBook(ctx, &schema.GetOrderBookRequest{LocationId: 60003760, TypeId: 34, Depth: proto.Int32(1)})
+	if assert.Nil(t, err) {
+		assert.Equal(t, 5.0, book.Top.BestSell)
+		assert.Equal(t, int64(120), book.Top.SellVolume)
+		assert.Equal(t, []*schema.PriceLevel{{Price: 5, Volume: 100, Orders: 1}}, book.Sell)
+	}
+
+	// Only the top of book when asked for no levels, and the default depth when unset
+	book, err = client.GetOrderBook(ctx, &schema.GetOrderBookRequest{LocationId: 60003760, TypeId: 34, Depth: proto.Int32(0)})
+	if assert.Nil(t, err) {
+		assert.Equal(t, 5.0, book.Top.BestSell)
+		assert.Empty(t, book.Sell)
+	}
+	book, err = client.GetOrderBook(ctx, &schema.GetOrderBookRequest{LocationId: 60003760, TypeId: 34})
+	if assert.Nil(t, err) {
+		assert.Len(t, book.Sell, 2)
+	}
+	_, err = client.GetOrderBook(ctx, &schema.GetOrderBookRequest{LocationId: 60003760, TypeId: 35})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetOrderBook(ctx, &schema.GetOrderBookRequest{LocationId: 60003760, TypeId: 34, Depth: proto.Int32(101)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.GetContract(ctx, &schema.GetContractRequest{ContractId: 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCSlowClient(t *testing.T) {
+	const region = int64(10000002)
+
+	mw, err := NewMarketWatch(DefaultConfig())
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	mw.createMarketStore(region)
+	now := time.Now()
+	for i := int64(1); i <= 50000; i++ {
+		mw.storeData(region, Order{Touched: now, Order: testOrder(i, 100, 5)})
+	}
+
+	// A fixed window so the snapshot fills it and the server waits on the client
+	client := dialGRPC(t, mw, grpc.WithInitialWindowSize(64*1024))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err = client.StreamOrders(ctx, &schema.StreamRequest{})
+	assert.Nil(t, err)
+	time.Sleep(200 * time.Millisecond)
+
+	// The workers can still add markets while it is not reading
+	created := make(chan struct{})
+	go func() {
+		mw.createMarketStore(1022734985679)
+		close(created)
+	}()
+	select {
+	case <-created:
+	case <-time.After(5 * time.Second):
+		t.Fatal("market store not created while a client was not reading")
+	}
+}
@@ -14,12 +14,12 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-func (s *MarketWatch) marketWorker(regionID int32) {
+func (s *MarketWatch) marketWorker(ctx context.Context, regionID int32) {
 	// For totalization
 	wg := sync.WaitGroup{}
 
-	// Loop forever
-	for {
+	// Loop until shutdown
+	for ctx.Err() == nil {
 		start := time.Now()
 		numOrders := 0
 
@@ -28,7 +28,7 @@ func (s *MarketWatch) marketWorker(regionID int32) {
 		echan := make(chan error, 100000)
 
 		orders, res, err := s.esi.ESI.MarketApi.GetMarketsRegionIdOrders(
-			context.Background(), "all", regionID, nil,
+			ctx, "all", regionID, nil,
 		)
 		if err != nil {
 			log.Println(err)
@@ -43,9 +43,9 @@ func (s *MarketWatch) marketWorker(regionID int32) {
 			continue
 		}
 		duration := timeUntilCacheExpires(res)
-		if duration.Minutes() < 3 {
+		if duration < s.config.MinimumCacheWindow {
 			fmt.Printf("%d market too close to window: waiting %s\n", regionID, duration.String())
-			time.Sleep(duration)
+			sleep(ctx, duration)
 			continue
 		}
 
@@ -56,7 +56,7 @@ func (s *MarketWatch) marketWorker(regionID int32) {
 				defer wg.Done() // release when done
 
 				orders, r, err := s.esi.ESI.MarketApi.GetMarketsRegionIdOrders(
-					context.Background(),
+					ctx,
 					"all",
 					regionID,
 					&esi.GetMarketsRegionIdOrdersOpts{Page: optional.NewInt32(page)},
@@ -68,7 +68,7 @@ func (s *MarketWatch) marketWorker(regionID int32) {
 
 				// Are we too close to the end of the window?
 				duration := timeUntilCacheExpires(r)
-				if duration.Seconds() < 20 {
+				if duration < s.config.MinimumPageWindow {
 					echan <- errors.New("market too close to end of window")
 					return
 				}
@@ -85,9 +85,18 @@ func (s *MarketWatch) marketWorker(regionID int32) {
 		close(rchan)
 		close(echan)
 
+		// Don't report a partial poll as deletions when shutting down
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Start over if any requests failed
+		failed := false
 		for err := range echan {
-			// Start over if any requests failed
 			log.Println(err)
+			failed = true
+		}
+		if failed {
 			continue
 		}
 
@@ -116,37 +125,23 @@ func (s *MarketWatch) marketWorker(regionID int32) {
 		).Observe(float64(time.Since(start).Nanoseconds()) / float64(time.Millisecond))
 
 		if len(newOrders) > 0 {
-			s.broadcast.Broadcast(
-				"market",
-				Message{
-					Action:  "addition",
-					Payload: newOrders,
-				},
-			)
+			s.publish(OrderAdded{RegionID: int64(regionID), Orders: newOrders, PolledAt: start})
 		}
 
 		if len(changes) > 0 {
-			s.broadcast.Broadcast(
-				"market",
-				Message{
-					Action:  "change",
-					Payload: changes,
-				},
-			)
+			s.publish(OrderChanged{RegionID: int64(regionID), Changes: changes, PolledAt: start})
 		}
 
 		if len(deletions) > 0 {
-			s.broadcast.Broadcast(
-				"market",
-				Message{
-					Action:  "deletion",
-					Payload: deletions,
-				},
-			)
+			s.publish(OrderDeleted{RegionID: int64(regionID), Deletions: deletions, PolledAt: start})
 		}
+		s.finishPoll("market", int64(regionID), start)
+
+		s.updateBooks(int64(regionID), start, newOrders, changes, deletions)
+		s.recordTrades(int64(regionID), start, changes, deletions)
 
 		// Sleep until the cache timer expires, plus a little.
-		time.Sleep(duration)
+		sleep(ctx, duration)
 	}
 }
 
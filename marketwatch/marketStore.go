@@ -27,6 +27,9 @@ type OrderChange struct {
 	Issued       time.Time `json:"issued,omitempty"`
 	Changed      bool      `json:"-"`
 	TimeChanged  time.Time `json:"time_changed"`
+
+	// original order size for trade inference, not sent to clients
+	volumeTotal int32
 }
 
 // storeData returns changes or true if the item is new
@@ -38,6 +41,7 @@ func (s *MarketWatch) storeData(locationID int64, order Order) (OrderChange, boo
 		Issued:      order.Order.Issued,
 		IsBuyOrder:  order.Order.IsBuyOrder,
 		TimeChanged: time.Now().UTC(), // We know this was within 5 minutes of this time
+		volumeTotal: order.Order.VolumeTotal,
 	}
 	sMap := s.getMarketStore(locationID)
 	v, loaded := sMap.LoadOrStore(order.Order.OrderId, order)
@@ -79,6 +83,7 @@ func (s *MarketWatch) expireOrders(locationID int64, t time.Time) []OrderChange
 					Price:        o.Order.Price,
 					Duration:     o.Order.Duration,
 					TimeChanged:  time.Now().UTC(), // We know this was within 5 minutes of this time
+					volumeTotal:  o.Order.VolumeTotal,
 				})
 			}
 			return true
@@ -99,11 +104,13 @@ func (s *MarketWatch) getMarketStore(locationID int64) *sync.Map {
 	return s.market[locationID]
 }
 
-// createMarketStore for a location
+// createMarketStore for a location, keeping any restored state
 func (s *MarketWatch) createMarketStore(locationID int64) {
 	s.mmutex.Lock()
 	defer s.mmutex.Unlock()
-	s.market[locationID] = &sync.Map{}
+	if s.market[locationID] == nil {
+		s.market[locationID] = &sync.Map{}
+	}
 }
 
 // getStructureState for a location
@@ -1,8 +1,10 @@
 package marketwatch
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"log"
-	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/antihax/goesi"
 	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
 )
 
 // MarketWatch provides CCP Market Data
@@ -18,14 +21,39 @@ type MarketWatch struct {
 	// goesi client
 	esi *goesi.APIClient
 
-	// websocket handler
+	// websocket handler, fed from a subscription
 	broadcast *wsbroadcast.Hub
 
+	// in process consumers of events
+	subscribers subscribers
+
 	// authentication
 	doAuth    bool
 	token     *oauth2.TokenSource
 	tokenAuth *goesi.SSOAuthenticator
 
+	// settings
+	config Config
+
+	// polling goroutines, waited on at shutdown
+	workers sync.WaitGroup
+
+	// sink writers, closed once the workers have stopped
+	sinking sync.WaitGroup
+
+	// optional persistence across restarts
+	snapshots SnapshotStore
+
+	// optional durable records of every event
+	sinks []Sink
+
+	// aggregated order books
+	books *orderBooks
+
+	// trades inferred from order changes and their candles
+	trades  *tradeLog
+	candles *candleStore
+
 	// data store
 	market     map[int64]*sync.Map
 	structures map[int64]*Structure
@@ -35,54 +63,50 @@ type MarketWatch struct {
 	smutex     sync.RWMutex // Structure mutex for the whole map
 }
 
-// NewMarketWatch creates a new MarketWatch microservice
-func NewMarketWatch(refresh, tokenClientID, tokenSecret string) *MarketWatch {
-	httpclient := &http.Client{
-		Transport: &ApiTransport{
-			next: &http.Transport{
-				MaxIdleConns: 200,
-				DialContext: (&net.Dialer{
-					Timeout:   300 * time.Second,
-					KeepAlive: 5 * 60 * time.Second,
-					DualStack: true,
-				}).DialContext,
-				IdleConnTimeout:       5 * 60 * time.Second,
-				TLSHandshakeTimeout:   10 * time.Second,
-				ResponseHeaderTimeout: 60 * time.Second,
-				ExpectContinueTimeout: 0,
-				MaxIdleConnsPerHost:   20,
-			},
-		},
-	}
+// NewMarketWatch creates a new MarketWatch microservice from a validated config.
+// It fails if a sink cannot be set up.
+func NewMarketWatch(config Config) (*MarketWatch, error) {
+	httpclient := config.ESI.httpClient(config.ConcurrentRequests)
 
 	// Setup an authenticator for our user tokens
 	doAuth := false
-	if tokenClientID == "" || tokenSecret == "" || refresh == "" {
+	if config.ClientID == "" || config.Secret == "" || config.RefreshToken == "" {
 		log.Println("Warning: Missing authentication parameters so only regional market will be polled")
 	} else {
 		doAuth = true
 	}
-	auth := goesi.NewSSOAuthenticator(httpclient, tokenClientID, tokenSecret, "", []string{})
+	auth := goesi.NewSSOAuthenticator(httpclient, config.ClientID, config.Secret, "", []string{})
+	if config.ESI.SSOAuthURL != "" {
+		auth.ChangeAuthURL(config.ESI.SSOAuthURL)
+	}
+	if config.ESI.SSOTokenURL != "" {
+		auth.ChangeTokenURL(config.ESI.SSOTokenURL)
+	}
 
 	tok := &oauth2.Token{
 		Expiry:       time.Now(),
 		AccessToken:  "",
-		RefreshToken: refresh,
+		RefreshToken: config.RefreshToken,
 		TokenType:    "Bearer",
 	}
 
 	// Build our private token
 	token := auth.TokenSource(tok)
 
-	return &MarketWatch{
+	s := &MarketWatch{
+		config: config,
+
 		// ESI Client
 		esi: goesi.NewAPIClient(
 			httpclient,
-			"eve-marketwatch",
+			config.ESI.userAgent(),
 		),
 
 		// Websocket Broadcaster
-		broadcast: wsbroadcast.NewHub([]string{"market", "contract"}),
+		broadcast: wsbroadcast.NewHub(
+			[]string{"market", "contract", "book", "trades", "candles"},
+			config.Websocket,
+		),
 
 		// ESI SSO Handler
 		doAuth:    doAuth,
@@ -90,25 +114,147 @@ func NewMarketWatch(refresh, tokenClientID, tokenSecret string) *MarketWatch {
 		tokenAuth: auth,
 
 		// Market Data Map
+		books:      newOrderBooks(),
+		trades:     &tradeLog{},
+		candles:    newCandleStore(config.CandleDir),
 		market:     make(map[int64]*sync.Map),
 		structures: make(map[int64]*Structure),
 		contracts:  make(map[int64]*sync.Map),
 	}
+
+	if config.ESI.URL != "" {
+		s.esi.ChangeBasePath(config.ESI.URL)
+	}
+
+	s.broadcast.AddEncoding("protobuf", protobufEncoding)
+
+	if config.Snapshot != "" {
+		s.snapshots = NewFileSnapshotStore(config.Snapshot)
+	}
+
+	// Before the webhooks start delivering, so nothing is left running on failure
+	if q := config.Sinks.SQL; q.Driver != "" {
+		db, err := sql.Open(q.Driver, q.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("sinks sql: %v", err)
+		}
+		sink, err := NewSQLSink(db, q.Driver, q.SoftDelete)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("sinks sql: %v", err)
+		}
+		s.AddSink(sink)
+	}
+	if n := config.Sinks.NDJSON; n.Dir != "" {
+		s.AddSink(NewNDJSONSink(n.Dir, n.MaxSize, n.Gzip))
+	}
+	for _, w := range config.Sinks.Webhooks {
+		s.AddSink(NewWebhookSink(w))
+	}
+
+	return s, nil
 }
 
-// Run starts listening on port 3005 for API requests
-func (s *MarketWatch) Run() error {
+// How long to wait for in-flight API requests when shutting down
+const shutdownTimeout = 20 * time.Second
+
+// Run starts listening on the configured address for websocket and API requests.
+// When the context is cancelled it stops polling, closes the websocket clients
+// and saves a final snapshot before returning.
+func (s *MarketWatch) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Pick up where we left off before starting the workers
+	if s.snapshots != nil {
+		s.restoreSnapshot()
+		s.spawn(ctx, s.runSnapshots)
+	}
+
+	// and the candles kept from before
+	s.candles.load(time.Now())
 
 	// Setup the callback to send the market to the client on connect
 	s.broadcast.OnRegister(s.dumpMarket)
-	go s.broadcast.Run()
+	hubDone := make(chan struct{})
+	go func() {
+		s.broadcast.Run(ctx)
+		close(hubDone)
+	}()
 
-	go s.startUpMarketWorkers()
+	// The websocket hub is just another subscriber, which drops its own slow clients
+	events := s.subscribe(ctx, nil, false, true)
+	go s.runBroadcast(events)
 
-	// Handler for the websocket
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	// Sinks outlive the workers so they get everything published before closing
+	sinkCtx, stopSinks := context.WithCancel(context.Background())
+	defer stopSinks()
+	s.startSinks(sinkCtx)
+
+	s.spawn(ctx, s.startUpMarketWorkers)
+	s.spawn(ctx, s.runCandles)
+
+	// Handler for the websocket, kept off the default mux so pprof stays on the metrics port
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		s.broadcast.ServeWs(w, r)
 	})
 
-	return http.ListenAndServe(":3005", nil)
+	// Server-Sent Events for clients that cannot hold a websocket
+	mux.HandleFunc("/events", s.broadcast.ServeEvents)
+
+	// Read only queries of the current state
+	s.registerAPI(mux)
+
+	server := &http.Server{Addr: s.config.Address, Handler: mux}
+	errc := make(chan error, 2)
+	go func() { errc <- server.ListenAndServe() }()
+
+	// gRPC streams the same data when configured
+	var grpcServer *grpc.Server
+	if s.config.GRPCAddress != "" {
+		grpcServer = s.newGRPCServer(ctx.Done())
+		go func() { errc <- s.serveGRPC(grpcServer) }()
+	}
+
+	var err error
+	select {
+	case err = <-errc:
+		// Neither server is any use without the other
+		server.Close()
+	case <-ctx.Done():
+		log.Println("shutting down")
+		shutdownCtx, done := context.WithTimeout(context.Background(), shutdownTimeout)
+		if serr := server.Shutdown(shutdownCtx); serr != nil {
+			log.Println(serr)
+		}
+		done()
+	}
+	cancel()
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+
+	// Stop polling, drain the sinks and let the clients go before saving where we got to
+	s.workers.Wait()
+	stopSinks()
+	s.sinking.Wait()
+	<-hubDone
+	if s.snapshots != nil {
+		s.saveSnapshot()
+	}
+
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// spawn runs a worker that is waited on at shutdown
+func (s *MarketWatch) spawn(ctx context.Context, f func(context.Context)) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		f(ctx)
+	}()
 }
@@ -1,59 +1,294 @@
 package marketwatch
 
 import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/antihax/eve-marketwatch/wsbroadcast"
 	"github.com/antihax/goesi/esi"
 )
 
-// Message wraps different payloads for the websocket interface
+// SchemaVersion of the message envelope and payloads described by
+// schema/marketwatch.schema.json. Bumped on any breaking change.
+const SchemaVersion = 1
+
+// Message wraps different payloads for the websocket interface.
+// The payload shape is decided by the action.
 type Message struct {
-	Action  string      `json:"action"`
-	Payload interface{} `json:"payload"`
+	SchemaVersion int         `json:"schema_version"`
+	Action        string      `json:"action"`
+	Stream        string      `json:"stream,omitempty"`
+	Sequence      uint64      `json:"seq,omitempty"`
+	RegionID      int64       `json:"region_id"`
+	Time          time.Time   `json:"time"`
+	Payload       interface{} `json:"payload"`
+}
+
+// newMessage for a payload found in a region (zero for structures) by a poll started at t
+func newMessage(action string, regionID int64, t time.Time, payload interface{}) Message {
+	return Message{
+		SchemaVersion: SchemaVersion,
+		Action:        action,
+		RegionID:      regionID,
+		Time:          t.UTC(),
+		Payload:       payload,
+	}
+}
+
+// WithSequence stamps the message with its position in the stream
+func (m Message) WithSequence(stream string, seq uint64) interface{} {
+	m.Stream = stream
+	m.Sequence = seq
+	return m
+}
+
+// Private is true for structure markets, which need an authenticated token to see
+func (m Message) Private() bool {
+	return m.RegionID == 0
 }
 
-func (s *MarketWatch) dumpMarket(channels map[string]bool, send chan interface{}) {
-	// Prevent changes to the map while we loop
-	s.mmutex.RLock()
-	defer s.mmutex.RUnlock()
+// Len is the number of orders or contracts in the payload
+func (m Message) Len() int {
+	switch p := m.Payload.(type) {
+	case []esi.GetMarketsRegionIdOrders200Ok:
+		return len(p)
+	case []OrderChange:
+		return len(p)
+	case []FullContract:
+		return len(p)
+	case []ContractChange:
+		return len(p)
+	case []BookTop:
+		return len(p)
+	case []Trade:
+		return len(p)
+	case []Candle:
+		return len(p)
+	}
+	return 1
+}
+
+// Filter reduces the payload to the orders or contracts a client asked for.
+func (m Message) Filter(f *wsbroadcast.Filter) (interface{}, bool) {
+	if !f.MatchRegion(m.RegionID) {
+		return nil, false
+	}
+	p, ok := filterPayload(f, m.Payload)
+	if !ok {
+		return nil, false
+	}
+	m.Payload = p
+	return m, true
+}
+
+// filterPayload reduces a batch to the items matching the filter's locations and types.
+// Returns false if nothing is left.
+func filterPayload(f *wsbroadcast.Filter, payload interface{}) (interface{}, bool) {
+	switch p := payload.(type) {
+	case []esi.GetMarketsRegionIdOrders200Ok:
+		o := []esi.GetMarketsRegionIdOrders200Ok{}
+		for i := range p {
+			if f.MatchLocation(p[i].LocationId) && f.MatchType(int64(p[i].TypeId)) {
+				o = append(o, p[i])
+			}
+		}
+		if len(o) == 0 {
+			return nil, false
+		}
+		return o, true
+
+	case []OrderChange:
+		o := []OrderChange{}
+		for i := range p {
+			if f.MatchLocation(p[i].LocationId) && f.MatchType(int64(p[i].TypeID)) {
+				o = append(o, p[i])
+			}
+		}
+		if len(o) == 0 {
+			return nil, false
+		}
+		return o, true
+
+	case []FullContract:
+		c := []FullContract{}
+		for i := range p {
+			if f.MatchLocation(p[i].Contract.StartLocationId) && matchContractTypes(f, p[i].typeIDs()) {
+				c = append(c, p[i])
+			}
+		}
+		if len(c) == 0 {
+			return nil, false
+		}
+		return c, true
+
+	case []ContractChange:
+		c := []ContractChange{}
+		for i := range p {
+			if f.MatchLocation(p[i].LocationId) && matchContractTypes(f, p[i].typeIDs) {
+				c = append(c, p[i])
+			}
+		}
+		if len(c) == 0 {
+			return nil, false
+		}
+		return c, true
+
+	case []BookTop:
+		b := []BookTop{}
+		for i := range p {
+			if f.MatchLocation(p[i].LocationID) && f.MatchType(int64(p[i].TypeID)) {
+				b = append(b, p[i])
+			}
+		}
+		if len(b) == 0 {
+			return nil, false
+		}
+		return b, true
 
+	case []Trade:
+		t := []Trade{}
+		for i := range p {
+			if f.MatchLocation(p[i].LocationID) && f.MatchType(int64(p[i].TypeID)) {
+				t = append(t, p[i])
+			}
+		}
+		if len(t) == 0 {
+			return nil, false
+		}
+		return t, true
+
+	case []Candle:
+		c := []Candle{}
+		for i := range p {
+			if f.MatchLocation(p[i].LocationID) && f.MatchType(int64(p[i].TypeID)) {
+				c = append(c, p[i])
+			}
+		}
+		if len(c) == 0 {
+			return nil, false
+		}
+		return c, true
+	}
+	return payload, true
+}
+
+// matchContractTypes is true if any item in the contract matches the filter
+func matchContractTypes(f *wsbroadcast.Filter, types []int32) bool {
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if f.MatchType(int64(t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// regionForLocation returns the region of a market store, or zero for structures.
+func (s *MarketWatch) regionForLocation(locationID int64) int64 {
+	if s.getStructureState(locationID) != nil {
+		return 0
+	}
+	return locationID
+}
+
+func (s *MarketWatch) dumpMarket(channels map[string]bool, filter *wsbroadcast.Filter, send chan interface{}) {
 	// loop all the locations
 	if channels["market"] {
-		for _, r := range s.market {
-			// Build a list
+		for l, r := range copyStores(&s.mmutex, s.market) {
+			// Build a list, stamped with the last poll that saw it
 			m := []esi.GetMarketsRegionIdOrders200Ok{}
+			polled := time.Time{}
 			r.Range(
 				func(k, v interface{}) bool {
 					o := v.(Order)
 					m = append(m, o.Order)
+					if o.Touched.After(polled) {
+						polled = o.Touched
+					}
 					return true
 				})
 			// send the list out
 			if len(m) > 0 {
-				send <- Message{
-					Action:  "addition",
-					Payload: m,
-				}
+				s.sendFiltered(send, filter, newMessage("addition", s.regionForLocation(l), polled, m))
 			}
 		}
 	}
 
 	// loop all the locations
 	if channels["contract"] {
-		for _, r := range s.contracts {
-			// Build a list
+		for l, r := range copyStores(&s.cmutex, s.contracts) {
+			// Build a list, stamped with the last poll that saw it
 			m := []FullContract{}
+			polled := time.Time{}
 			r.Range(
 				func(k, v interface{}) bool {
 					o := v.(Contract)
 					m = append(m, o.Contract)
+					if o.Touched.After(polled) {
+						polled = o.Touched
+					}
 					return true
 				})
 			// send the list out
 			if len(m) > 0 {
-				send <- Message{
-					Action:  "contractAddition",
-					Payload: m,
-				}
+				s.sendFiltered(send, filter, newMessage("contractAddition", l, polled, m))
 			}
 		}
 	}
+
+	// send the current tops of book
+	if channels["book"] {
+		for region, tops := range s.books.tops() {
+			s.sendFiltered(send, filter, newMessage("bookChange", region, time.Now(), tops))
+		}
+	}
+}
+
+// copyStores copies the stores out under the lock, so a slow client
+// being sent the dump never holds up the workers
+func copyStores(mutex *sync.RWMutex, stores map[int64]*sync.Map) map[int64]*sync.Map {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	c := make(map[int64]*sync.Map, len(stores))
+	for l, r := range stores {
+		c[l] = r
+	}
+	return c
+}
+
+// sendFiltered sends what is left of a message after filtering,
+// split into chunks of the configured size
+func (s *MarketWatch) sendFiltered(send chan interface{}, filter *wsbroadcast.Filter, m Message) {
+	if !filter.Empty() {
+		fm, ok := m.Filter(filter)
+		if !ok {
+			return
+		}
+		m = fm.(Message)
+	}
+	for _, c := range m.chunks(s.config.Websocket.SnapshotChunk) {
+		send <- c
+	}
+}
+
+// chunks splits the payload into messages of at most size items
+func (m Message) chunks(size int) []Message {
+	p := reflect.ValueOf(m.Payload)
+	if p.Kind() != reflect.Slice || p.Len() <= size {
+		return []Message{m}
+	}
+	chunks := make([]Message, 0, (p.Len()+size-1)/size)
+	for i := 0; i < p.Len(); i += size {
+		end := i + size
+		if end > p.Len() {
+			end = p.Len()
+		}
+		c := m
+		c.Payload = p.Slice(i, end).Interface()
+		chunks = append(chunks, c)
+	}
+	return chunks
 }
@@ -0,0 +1,112 @@
+package marketwatch
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NDJSONSink writes each event as a line of JSON in the same format as the
+// websocket. A new file is started every hour, and whenever one reaches the
+// size limit, named events-YYYYMMDDHH-N.ndjson with .gz when compressed.
+type NDJSONSink struct {
+	dir      string
+	maxSize  int64
+	compress bool
+
+	// current file, the hour it is for and the bytes of JSON written to it
+	file *os.File
+	gz   *gzip.Writer
+	w    io.Writer
+	hour time.Time
+	size int64
+
+	// clock, replaced in tests
+	now func() time.Time
+}
+
+// NewNDJSONSink creates a sink writing to dir, which is created if missing.
+// maxSize is the bytes of JSON per file before compression, hourly only when zero.
+func NewNDJSONSink(dir string, maxSize int64, compress bool) *NDJSONSink {
+	return &NDJSONSink{dir: dir, maxSize: maxSize, compress: compress, now: time.Now}
+}
+
+// Write appends the event to the current file, starting a new one if it is due
+func (n *NDJSONSink) Write(e Event) error {
+	b, err := json.Marshal(eventMessage(e))
+	if err != nil {
+		return err
+	}
+
+	hour := n.now().UTC().Truncate(time.Hour)
+	if n.file == nil || !hour.Equal(n.hour) || (n.maxSize > 0 && n.size >= n.maxSize) {
+		if err := n.rotate(hour); err != nil {
+			return err
+		}
+	}
+
+	if _, err := n.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	n.size += int64(len(b)) + 1
+
+	// Every event reaches the OS once written, so it survives the process crashing
+	// but not the host, as nothing is synced to disk
+	if n.gz != nil {
+		return n.gz.Flush()
+	}
+	return nil
+}
+
+// Close finishes the current file
+func (n *NDJSONSink) Close() error {
+	if n.file == nil {
+		return nil
+	}
+	var err error
+	if n.gz != nil {
+		err = n.gz.Close()
+	}
+	if cerr := n.file.Close(); err == nil {
+		err = cerr
+	}
+	n.file, n.gz, n.w = nil, nil, nil
+	return err
+}
+
+// rotate closes the current file and opens the next unused one for the hour
+func (n *NDJSONSink) rotate(hour time.Time) error {
+	if err := n.Close(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(n.dir, 0755); err != nil {
+		return err
+	}
+
+	ext := ".ndjson"
+	if n.compress {
+		ext += ".gz"
+	}
+
+	// Never append to a file from before a restart
+	var f *os.File
+	for part := 0; f == nil; part++ {
+		name := filepath.Join(n.dir, fmt.Sprintf("events-%s-%d%s", hour.Format("2006010215"), part, ext))
+		var err error
+		f, err = os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+
+	n.file, n.w, n.hour, n.size = f, f, hour, 0
+	if n.compress {
+		n.gz = gzip.NewWriter(f)
+		n.w = n.gz
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package marketwatch
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/antihax/goesi/esi"
+	"github.com/stretchr/testify/assert"
+)
+
+// readNDJSON reads the messages back from a file
+func readNDJSON(t *testing.T, path string) []frame {
+	f, err := os.Open(path)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if filepath.Ext(path) == ".gz" {
+		gz, err := gzip.NewReader(f)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	frames := []frame{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fr := frame{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &fr))
+		frames = append(frames, fr)
+	}
+	assert.Nil(t, scanner.Err())
+	return frames
+}
+
+// sinkFiles lists the files a sink wrote, in name order
+func sinkFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "events-*"))
+	assert.Nil(t, err)
+	sort.Strings(files)
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	return files
+}
+
+func TestNDJSONSink(t *testing.T) {
+	polled := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	added := OrderAdded{RegionID: 10000002, Orders: []esi.GetMarketsRegionIdOrders200Ok{testOrder(1, 100, 5)}, PolledAt: polled}
+	deleted := OrderDeleted{RegionID: 10000002, Deletions: []OrderChange{{OrderID: 1, VolumeChange: 100}}, PolledAt: polled}
+
+	for _, compress := range []bool{false, true} {
+		dir := t.TempDir()
+		ext := ".ndjson"
+		if compress {
+			ext += ".gz"
+		}
+
+		now := polled.Add(5 * time.Minute)
+		sink := NewNDJSONSink(dir, 1, compress)
+		sink.now = func() time.Time { return now }
+
+		// Every line fills a file, then the hour moves on
+		assert.Nil(t, sink.Write(added))
+		assert.Nil(t, sink.Write(deleted))
+		now = now.Add(time.Hour)
+		assert.Nil(t, sink.Write(added))
+		assert.Nil(t, sink.Close())
+
+		// A restart carries on with new files
+		sink = NewNDJSONSink(dir, 0, compress)
+		sink.now = func() time.Time { return now }
+		assert.Nil(t, sink.Write(deleted))
+		assert.Nil(t, sink.Close())
+
+		assert.Equal(t, []string{
+			"events-2026050110-0" + ext,
+			"events-2026050110-1" + ext,
+			"events-2026050111-0" + ext,
+			"events-2026050111-1" + ext,
+		}, sinkFiles(t, dir))
+
+		frames := readNDJSON(t, filepath.Join(dir, "events-2026050110-0"+ext))
+		if assert.Len(t, frames, 1) {
+			assert.Equal(t, SchemaVersion, frames[0].SchemaVersion)
+			assert.Equal(t, "addition", frames[0].Action)
+			assert.Equal(t, int64(10000002), frames[0].RegionID)
+			assert.Equal(t, polled, frames[0].Time)
+			orders := []esi.GetMarketsRegionIdOrders200Ok{}
+			assert.Nil(t, json.Unmarshal(frames[0].Payload, &orders))
+			assert.Equal(t, added.Orders, orders)
+		}
+		frames = readNDJSON(t, filepath.Join(dir, "events-2026050111-1"+ext))
+		if assert.Len(t, frames, 1) {
+			assert.Equal(t, "deletion", frames[0].Action)
+		}
+	}
+}
+
+// recordingSink keeps what it is sent
+type recordingSink struct {
+	events chan Event
+	closed chan struct{}
+}
+
+func (r *recordingSink) Write(e Event) error {
+	r.events <- e
+	return nil
+}
+
+func (r *recordingSink) Close() error {
+	close(r.closed)
+	return nil
+}
+
+func TestSinks(t *testing.T) {
+	const region = int32(10000002)
+
+	f := newFakeESI()
+	defer f.Close()
+	f.setRegions(region)
+	f.setOrders(region, testOrder(1, 100, 5))
+
+	config := DefaultConfig()
+	config.Scope.DisableContracts = true
+	mw := newTestMarketWatch(t, f, config)
+	sink := &recordingSink{events: make(chan Event, 100), closed: make(chan struct{})}
+	mw.AddSink(sink)
+
+	// Closed once the service has stopped
+	t.Cleanup(func() {
+		select {
+		case <-sink.closed:
+		default:
+			t.Error("sink not closed")
+		}
+	})
+	startMarketWatch(t, mw)
+
+	assert.IsType(t, OrderAdded{}, nextEvent(t, sink.events))
+	assert.IsType(t, BookChanged{}, nextEvent(t, sink.events))
+}
+
+func TestSinksAtShutdown(t *testing.T) {
+	f := newFakeESI()
+	defer f.Close()
+
+	config := DefaultConfig()
+	config.Scope.DisableMarkets = true
+	config.Scope.DisableContracts = true
+	mw := newTestMarketWatch(t, f, config)
+	sink := &recordingSink{events: make(chan Event, 100), closed: make(chan struct{})}
+	mw.AddSink(sink)
+
+	// A poll that finishes after the service is told to stop
+	ctx, cancel := context.WithCancel(context.Background())
+	mw.spawn(ctx, func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		mw.publish(OrderAdded{RegionID: 10000002, Orders: []esi.GetMarketsRegionIdOrders200Ok{testOrder(1, 100, 5)}})
+	})
+	done := make(chan error, 1)
+	go func() { done <- mw.Run(ctx) }()
+	cancel()
+	assert.Nil(t, <-done)
+
+	// still reaches the sink before it is closed
+	select {
+	case <-sink.closed:
+	default:
+		t.Error("sink not closed")
+	}
+	assert.IsType(t, OrderAdded{}, nextEvent(t, sink.events))
+}
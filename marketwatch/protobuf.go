@@ -0,0 +1,298 @@
+package marketwatch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/antihax/eve-marketwatch/schema"
+	"github.com/antihax/eve-marketwatch/wsbroadcast"
+	"github.com/antihax/goesi/esi"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// protobufEncoding writes each message as a schema.Frame
+var protobufEncoding = wsbroadcast.Encoding{Binary: true, Marshal: marshalProto}
+
+func marshalProto(m interface{}) ([]byte, error) {
+	frame, err := protoFrame(m)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(frame)
+}
+
+// protoFrame converts a broadcast, a Reply or a SnapshotMarker
+func protoFrame(m interface{}) (*schema.Frame, error) {
+	var frame *schema.Frame
+	switch m := m.(type) {
+	case Message:
+		frame = &schema.Frame{
+			SchemaVersion: uint32(m.SchemaVersion),
+			Action:        m.Action,
+			Stream:        m.Stream,
+			Seq:           m.Sequence,
+			RegionId:      m.RegionID,
+			Time:          protoTime(m.Time),
+		}
+		if err := setProtoPayload(frame, m.Payload); err != nil {
+			return nil, err
+		}
+	case wsbroadcast.Reply:
+		frame = &schema.Frame{
+			Action:  m.Action,
+			Payload: &schema.Frame_Ack{Ack: protoAck(m.Payload)},
+		}
+	case wsbroadcast.SnapshotMarker:
+		frame = &schema.Frame{
+			Action: m.Action,
+			Payload: &schema.Frame_Snapshot{Snapshot: &schema.Snapshot{
+				Stream:   m.Payload.Stream,
+				Seq:      m.Payload.Sequence,
+				Channels: m.Payload.Channels,
+				Messages: int64(m.Payload.Messages),
+				Items:    int64(m.Payload.Items),
+			}},
+		}
+	default:
+		return nil, fmt.Errorf("protobuf: cannot encode %T", m)
+	}
+	return frame, nil
+}
+
+// setProtoPayload converts the payload into the matching field of the frame
+func setProtoPayload(frame *schema.Frame, payload interface{}) error {
+	switch p := payload.(type) {
+	case []esi.GetMarketsRegionIdOrders200Ok:
+		items := make([]*schema.Order, len(p))
+		for i := range p {
+			items[i] = protoOrder(&p[i])
+		}
+		frame.Payload = &schema.Frame_Orders{Orders: &schema.Orders{Items: items}}
+
+	case []OrderChange:
+		items := make([]*schema.OrderChange, len(p))
+		for i := range p {
+			items[i] = protoOrderChange(&p[i])
+		}
+		frame.Payload = &schema.Frame_OrderChanges{OrderChanges: &schema.OrderChanges{Items: items}}
+
+	case []FullContract:
+		items := make([]*schema.FullContract, len(p))
+		for i := range p {
+			items[i] = protoFullContract(&p[i])
+		}
+		frame.Payload = &schema.Frame_Contracts{Contracts: &schema.Contracts{Items: items}}
+
+	case []ContractChange:
+		items := make([]*schema.ContractChange, len(p))
+		for i := range p {
+			items[i] = protoContractChange(&p[i])
+		}
+		frame.Payload = &schema.Frame_ContractChanges{ContractChanges: &schema.ContractChanges{Items: items}}
+
+	case []BookTop:
+		items := make([]*schema.BookTop, len(p))
+		for i := range p {
+			items[i] = protoBookTop(&p[i])
+		}
+		frame.Payload = &schema.Frame_BookTops{BookTops: &schema.BookTops{Items: items}}
+
+	case []Trade:
+		items := make([]*schema.Trade, len(p))
+		for i := range p {
+			items[i] = protoTrade(&p[i])
+		}
+		frame.Payload = &schema.Frame_Trades{Trades: &schema.Trades{Items: items}}
+
+	case []Candle:
+		items := make([]*schema.Candle, len(p))
+		for i := range p {
+			items[i] = protoCandle(&p[i])
+		}
+		frame.Payload = &schema.Frame_Candles{Candles: &schema.Candles{Items: items}}
+
+	default:
+		return fmt.Errorf("protobuf: cannot encode %T payload", payload)
+	}
+	return nil
+}
+
+// protoTime leaves zero times unset
+func protoTime(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func protoOrder(o *esi.GetMarketsRegionIdOrders200Ok) *schema.Order {
+	return &schema.Order{
+		OrderId:      o.OrderId,
+		LocationId:   o.LocationId,
+		SystemId:     o.SystemId,
+		TypeId:       o.TypeId,
+		IsBuyOrder:   o.IsBuyOrder,
+		Price:        o.Price,
+		VolumeRemain: o.VolumeRemain,
+		VolumeTotal:  o.VolumeTotal,
+		MinVolume:    o.MinVolume,
+		Range:        o.Range_,
+		Duration:     o.Duration,
+		Issued:       protoTime(o.Issued),
+	}
+}
+
+func protoOrderChange(c *OrderChange) *schema.OrderChange {
+	return &schema.OrderChange{
+		OrderId:      c.OrderID,
+		LocationId:   c.LocationId,
+		TypeId:       c.TypeID,
+		VolumeChange: c.VolumeChange,
+		VolumeRemain: c.VolumeRemain,
+		Price:        c.Price,
+		Duration:     c.Duration,
+		IsBuyOrder:   c.IsBuyOrder,
+		Issued:       protoTime(c.Issued),
+		TimeChanged:  protoTime(c.TimeChanged),
+	}
+}
+
+func protoFullContract(c *FullContract) *schema.FullContract {
+	contract := &c.Contract
+	full := &schema.FullContract{
+		Contract: &schema.Contract{
+			ContractId:          contract.ContractId,
+			Type:                contract.Type_,
+			IssuerId:            contract.IssuerId,
+			IssuerCorporationId: contract.IssuerCorporationId,
+			ForCorporation:      contract.ForCorporation,
+			StartLocationId:     contract.StartLocationId,
+			EndLocationId:       contract.EndLocationId,
+			Title:               contract.Title,
+			Price:               contract.Price,
+			Buyout:              contract.Buyout,
+			Reward:              contract.Reward,
+			Collateral:          contract.Collateral,
+			Volume:              contract.Volume,
+			DaysToComplete:      contract.DaysToComplete,
+			DateIssued:          protoTime(contract.DateIssued),
+			DateExpired:         protoTime(contract.DateExpired),
+		},
+		Bids: protoBids(c.Bids),
+	}
+	for _, i := range c.Items {
+		full.Items = append(full.Items, &schema.ContractItem{
+			RecordId:           i.RecordId,
+			ItemId:             i.ItemId,
+			TypeId:             i.TypeId,
+			Quantity:           i.Quantity,
+			IsIncluded:         i.IsIncluded,
+			IsBlueprintCopy:    i.IsBlueprintCopy,
+			MaterialEfficiency: i.MaterialEfficiency,
+			TimeEfficiency:     i.TimeEfficiency,
+			Runs:               i.Runs,
+		})
+	}
+	return full
+}
+
+func protoBids(bids []esi.GetContractsPublicBidsContractId200Ok) []*schema.ContractBid {
+	var p []*schema.ContractBid
+	for _, b := range bids {
+		p = append(p, &schema.ContractBid{
+			BidId:   b.BidId,
+			Amount:  b.Amount,
+			DateBid: protoTime(b.DateBid),
+		})
+	}
+	return p
+}
+
+func protoContractChange(c *ContractChange) *schema.ContractChange {
+	return &schema.ContractChange{
+		ContractId:  c.ContractId,
+		LocationId:  c.LocationId,
+		Expired:     c.Expired,
+		DateExpired: protoTime(c.DateExpired),
+		Bids:        protoBids(c.Bids),
+		Price:       c.Price,
+		Type:        c.Type_,
+		TimeChanged: protoTime(c.TimeChanged),
+	}
+}
+
+func protoBookTop(b *BookTop) *schema.BookTop {
+	return &schema.BookTop{
+		LocationId:  b.LocationID,
+		TypeId:      b.TypeID,
+		BestBuy:     b.BestBuy,
+		BestSell:    b.BestSell,
+		Spread:      b.Spread,
+		BuyVolume:   b.BuyVolume,
+		SellVolume:  b.SellVolume,
+		BuyOrders:   int32(b.BuyOrders),
+		SellOrders:  int32(b.SellOrders),
+		TimeChanged: protoTime(b.TimeChanged),
+	}
+}
+
+func protoBook(b *Book) *schema.OrderBook {
+	book := &schema.OrderBook{Top: protoBookTop(&b.BookTop)}
+	for _, l := range b.Buy {
+		book.Buy = append(book.Buy, protoPriceLevel(l))
+	}
+	for _, l := range b.Sell {
+		book.Sell = append(book.Sell, protoPriceLevel(l))
+	}
+	return book
+}
+
+func protoPriceLevel(l PriceLevel) *schema.PriceLevel {
+	return &schema.PriceLevel{Price: l.Price, Volume: l.Volume, Orders: int32(l.Orders)}
+}
+
+func protoTrade(t *Trade) *schema.Trade {
+	return &schema.Trade{
+		OrderId:    t.OrderID,
+		LocationId: t.LocationID,
+		RegionId:   t.RegionID,
+		TypeId:     t.TypeID,
+		Price:      t.Price,
+		Quantity:   t.Quantity,
+		Side:       t.Side,
+		Filled:     t.Filled,
+		Time:       protoTime(t.Time),
+	}
+}
+
+func protoCandle(c *Candle) *schema.Candle {
+	return &schema.Candle{
+		Interval:   c.Interval,
+		RegionId:   c.RegionID,
+		LocationId: c.LocationID,
+		TypeId:     c.TypeID,
+		Start:      protoTime(c.Start),
+		Open:       c.Open,
+		High:       c.High,
+		Low:        c.Low,
+		Close:      c.Close,
+		Volume:     c.Volume,
+		Trades:     int32(c.Trades),
+		Closed:     c.Closed,
+	}
+}
+
+func protoAck(a wsbroadcast.Ack) *schema.Ack {
+	ack := &schema.Ack{
+		Id:       a.ID,
+		Command:  a.Command,
+		Channels: a.Channels,
+		Error:    a.Error,
+	}
+	if !a.Filter.Empty() {
+		ack.Filter = &schema.Filter{}
+		ack.Filter.Region, ack.Filter.Location, ack.Filter.Type = a.Filter.IDs()
+	}
+	return ack
+}
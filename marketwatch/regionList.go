@@ -6,10 +6,13 @@ import (
 	"time"
 )
 
-func (s *MarketWatch) startUpMarketWorkers() {
+func (s *MarketWatch) startUpMarketWorkers(ctx context.Context) {
 	// Get all the regions and fire up workers for each
-	regions, _, err := s.esi.ESI.UniverseApi.GetUniverseRegions(context.Background(), nil)
+	regions, _, err := s.esi.ESI.UniverseApi.GetUniverseRegions(ctx, nil)
 	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
 		log.Fatal(err)
 	}
 
@@ -17,15 +20,22 @@ func (s *MarketWatch) startUpMarketWorkers() {
 		// Prebuild the maps
 		s.createMarketStore(int64(region))
 		s.createContractStore(int64(region))
-		// Ignore non-market regions
-		if region < 11000000 || region == 11000031 {
-			time.Sleep(time.Millisecond * 500)
-			go s.marketWorker(region)
-			go s.contractWorker(region)
+		// Ignore non-market regions and anything we were told to skip
+		if (region < 11000000 || region == 11000031) && s.config.Scope.allowRegion(int64(region)) {
+			if !sleep(ctx, time.Millisecond*500) {
+				return
+			}
+			region := region
+			if !s.config.Scope.DisableMarkets {
+				s.spawn(ctx, func(ctx context.Context) { s.marketWorker(ctx, region) })
+			}
+			if !s.config.Scope.DisableContracts {
+				s.spawn(ctx, func(ctx context.Context) { s.contractWorker(ctx, region) })
+			}
 		}
 	}
 
-	if s.doAuth {
-		go s.runStructures()
+	if s.doAuth && !s.config.Scope.DisableMarkets {
+		s.spawn(ctx, s.runStructures)
 	}
 }
@@ -0,0 +1,57 @@
+package marketwatch
+
+// Scope limits which regions and structures are polled.
+// Empty lists allow everything.
+type Scope struct {
+	Regions           []int64 `yaml:"regions"`
+	ExcludeRegions    []int64 `yaml:"exclude_regions"`
+	Structures        []int64 `yaml:"structures"`
+	ExcludeStructures []int64 `yaml:"exclude_structures"`
+
+	// Turn off polling of markets (including structures) or contracts
+	DisableMarkets   bool `yaml:"disable_markets"`
+	DisableContracts bool `yaml:"disable_contracts"`
+}
+
+// allowRegion checks if a region should be polled
+func (sc *Scope) allowRegion(regionID int64) bool {
+	return allowID(regionID, sc.Regions, sc.ExcludeRegions)
+}
+
+// allowStructure checks if a structure should be polled
+func (sc *Scope) allowStructure(structureID int64) bool {
+	return allowID(structureID, sc.Structures, sc.ExcludeStructures)
+}
+
+// allowMarket checks if the market of a region or structure is polled
+func (sc *Scope) allowMarket(locationID int64, structure bool) bool {
+	if sc.DisableMarkets {
+		return false
+	}
+	if structure {
+		return sc.allowStructure(locationID)
+	}
+	return sc.allowRegion(locationID)
+}
+
+// allowContracts checks if the contracts of a region are polled
+func (sc *Scope) allowContracts(regionID int64) bool {
+	return !sc.DisableContracts && sc.allowRegion(regionID)
+}
+
+func allowID(id int64, allow, deny []int64) bool {
+	for _, d := range deny {
+		if d == id {
+			return false
+		}
+	}
+	if len(allow) == 0 {
+		return true
+	}
+	for _, a := range allow {
+		if a == id {
+			return true
+		}
+	}
+	return false
+}
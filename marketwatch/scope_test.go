@@ -0,0 +1,92 @@
+package marketwatch
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScope(t *testing.T) {
+	sc := Scope{Regions: []int64{10000002, 10000043}, ExcludeRegions: []int64{10000043}, ExcludeStructures: []int64{2}}
+	assert.True(t, sc.allowRegion(10000002))
+	assert.False(t, sc.allowRegion(10000043))
+	assert.False(t, sc.allowRegion(10000030))
+	assert.True(t, sc.allowStructure(1))
+	assert.False(t, sc.allowStructure(2))
+
+	assert.True(t, sc.allowMarket(10000002, false))
+	assert.True(t, sc.allowMarket(1, true))
+	assert.False(t, sc.allowMarket(2, true))
+	assert.True(t, sc.allowContracts(10000002))
+	assert.False(t, sc.allowContracts(10000043))
+
+	sc.DisableMarkets = true
+	assert.False(t, sc.allowMarket(10000002, false))
+	assert.False(t, sc.allowMarket(1, true))
+	assert.True(t, sc.allowContracts(10000002))
+	sc.DisableContracts = true
+	assert.False(t, sc.allowContracts(10000002))
+}
+
+func TestScopeWorkers(t *testing.T) {
+	const (
+		region    = int32(10000002)
+		skipped   = int32(10000043)
+		wormholes = int32(11000001)
+		structure = int64(1022734985679)
+		excluded  = int64(1022734985680)
+	)
+	orders := func(regionID int32) string { return fmt.Sprintf("/v1/markets/%d/orders/", regionID) }
+	contracts := func(regionID int32) string { return fmt.Sprintf("/v1/contracts/public/%d/", regionID) }
+	structureOrders := func(structureID int64) string { return fmt.Sprintf("/v1/markets/structures/%d/", structureID) }
+
+	// Only the markets of the region and structure in scope are polled
+	t.Run("markets", func(t *testing.T) {
+		f := newFakeESI()
+		defer f.Close()
+		f.setRegions(region, skipped, wormholes)
+		f.setStructures(structure, excluded)
+		f.setOrders(region, testOrder(1, 100, 5))
+		f.setContracts(region)
+		f.setStructureOrders(structure)
+
+		config := DefaultConfig()
+		config.ClientID = "client"
+		config.Secret = "secret"
+		config.RefreshToken = "refresh"
+		config.Scope.ExcludeRegions = []int64{int64(skipped)}
+		config.Scope.ExcludeStructures = []int64{excluded}
+		config.Scope.DisableContracts = true
+		startMarketWatch(t, newTestMarketWatch(t, f, config))
+		assert.Eventually(t, func() bool {
+			return f.served(orders(region), 1) > 1 && f.served(structureOrders(structure), 1) > 1
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, 0, f.served(orders(skipped), 1))
+		assert.Equal(t, 0, f.served(orders(wormholes), 1))
+		assert.Equal(t, 0, f.served(structureOrders(excluded), 1))
+		assert.Equal(t, 0, f.served(contracts(region), 1))
+	})
+
+	// Or only the contracts, without looking for structures
+	t.Run("contracts", func(t *testing.T) {
+		f := newFakeESI()
+		defer f.Close()
+		f.setRegions(region)
+		f.setStructures(structure)
+		f.setContracts(region)
+
+		config := DefaultConfig()
+		config.ClientID = "client"
+		config.Secret = "secret"
+		config.RefreshToken = "refresh"
+		config.Scope.DisableMarkets = true
+		startMarketWatch(t, newTestMarketWatch(t, f, config))
+		assert.Eventually(t, func() bool {
+			return f.served(contracts(region), 1) > 1
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, 0, f.served(orders(region), 1))
+		assert.Equal(t, 0, f.served("/v1/universe/structures/", 1))
+	})
+}
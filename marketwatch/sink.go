@@ -0,0 +1,177 @@
+package marketwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Sink durably records every event polling finds, to rebuild a database or
+// backtest trade inference without a consumer connected all the time.
+type Sink interface {
+	// Write an event. Polling waits on sinks, so a slow sink slows polling.
+	Write(Event) error
+
+	// Close flushes anything buffered once there are no more events
+	Close() error
+}
+
+// PollSink is a Sink that applies each poll as a whole, such as a database
+// mirroring the current state. It is reset to the state when the service
+// starts, then told as each poll finishes writing its events.
+type PollSink interface {
+	Sink
+
+	// Reset replaces everything with a snapshot. Called before any events,
+	// and again after a poll fails to apply so the sink catches up.
+	Reset(*Snapshot) error
+
+	// PollFinished is called once every event a poll found has been written
+	PollFinished(Poll) error
+}
+
+// Poll identifies one poll of a market or a region's contracts
+type Poll struct {
+	// market or contract
+	Channel string
+
+	// Region polled, zero for structure markets
+	RegionID int64
+
+	// When the poll started, the PollTime of its events
+	PolledAt time.Time
+}
+
+// pollFinished follows the events of a poll to sinks. Never published to other subscribers.
+type pollFinished struct{ Poll }
+
+func (e pollFinished) Action() string                  { return "pollFinished" }
+func (e pollFinished) Region() int64                   { return e.RegionID }
+func (e pollFinished) PollTime() time.Time             { return e.PolledAt }
+func (e pollFinished) channel() string                 { return e.Channel }
+func (e pollFinished) payload() interface{}            { return nil }
+func (e pollFinished) withPayload(p interface{}) Event { return e }
+
+// SinkConfig turns on the built in sinks
+type SinkConfig struct {
+	NDJSON   NDJSONConfig    `yaml:"ndjson"`
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+	SQL      SQLConfig       `yaml:"sql"`
+}
+
+// NDJSONConfig writes events to rotating newline delimited JSON files
+type NDJSONConfig struct {
+	// Directory to write to, off when empty
+	Dir string `yaml:"dir"`
+
+	// Start another file once one holds this many bytes of JSON, hourly only when zero
+	MaxSize int64 `yaml:"max_size"`
+
+	// Compress the files with gzip
+	Gzip bool `yaml:"gzip"`
+}
+
+// SQLConfig mirrors the orders and contracts into a database
+type SQLConfig struct {
+	// postgres or sqlite3, off when empty
+	Driver string `yaml:"driver"`
+
+	// Connection string for the driver
+	DSN string `yaml:"dsn"`
+
+	// Set deleted_at on orders and contracts that are gone rather than removing them
+	SoftDelete bool `yaml:"soft_delete"`
+}
+
+// Validate checks the settings are usable
+func (c *SinkConfig) Validate() error {
+	if c.SQL.Driver != "" {
+		if _, ok := sqlDialects[c.SQL.Driver]; !ok {
+			return fmt.Errorf("sinks sql driver %q must be postgres or sqlite3", c.SQL.Driver)
+		}
+	}
+	if c.NDJSON.MaxSize < 0 {
+		return errors.New("sinks ndjson max_size cannot be negative")
+	}
+	for i := range c.Webhooks {
+		if err := c.Webhooks[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddSink records every event to a sink while running. Must be called before Run.
+func (s *MarketWatch) AddSink(sink Sink) {
+	s.sinks = append(s.sinks, sink)
+}
+
+// runSink writes events to a sink until the subscription ends, then closes it
+func (s *MarketWatch) runSink(sink Sink, events <-chan Event) {
+	poll, isPoll := sink.(PollSink)
+	synced := !isPoll || s.resetSink(poll)
+	for e := range events {
+		p, finished := e.(pollFinished)
+		switch {
+		case !finished:
+			sinkError(sink, sink.Write(e))
+		case isPoll:
+			// A poll that failed leaves the sink behind, so start it over from the current state
+			if !sinkError(sink, poll.PollFinished(p.Poll)) || !synced {
+				synced = s.resetSink(poll)
+			}
+		}
+	}
+	if err := sink.Close(); err != nil {
+		log.Printf("sink %T: %v\n", sink, err)
+	}
+}
+
+// resetSink brings a sink up to the current state, returning whether it worked.
+// Anything published after the snapshot is still to come, so nothing is missed.
+func (s *MarketWatch) resetSink(sink PollSink) bool {
+	return sinkError(sink, sink.Reset(s.takeSnapshot()))
+}
+
+// sinkError logs and counts a failed write, returning true when there was none
+func sinkError(sink Sink, err error) bool {
+	if err == nil {
+		return true
+	}
+	log.Printf("sink %T: %v\n", sink, err)
+	metricSinkErrors.Inc()
+	return false
+}
+
+// startSinks subscribes each sink to every event until the context is cancelled
+func (s *MarketWatch) startSinks(ctx context.Context) {
+	for _, sink := range s.sinks {
+		_, isPoll := sink.(PollSink)
+		sink, events := sink, s.subscribe(ctx, nil, isPoll, true)
+		s.sinking.Add(1)
+		go func() {
+			defer s.sinking.Done()
+			s.runSink(sink, events)
+		}()
+	}
+}
+
+// Metrics
+var (
+	metricSinkErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "evemarketwatch",
+		Subsystem: "sink",
+		Name:      "errors",
+		Help:      "Events that failed to be written to a sink.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		metricSinkErrors,
+	)
+}
@@ -0,0 +1,196 @@
+package marketwatch
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/antihax/goesi/esi"
+)
+
+// Snapshot of the market and contract state
+type Snapshot struct {
+	Time       time.Time
+	Markets    map[int64][]Order
+	Contracts  map[int64][]Contract
+	Structures []int64
+}
+
+// SnapshotStore saves and restores state across restarts.
+type SnapshotStore interface {
+	// Save replaces the stored snapshot
+	Save(*Snapshot) error
+
+	// Load returns the stored snapshot, or nil if there is none
+	Load() (*Snapshot, error)
+}
+
+// FileSnapshotStore keeps a gzipped gob snapshot in a single file.
+type FileSnapshotStore struct {
+	path string
+}
+
+// NewFileSnapshotStore creates a snapshot store at path
+func NewFileSnapshotStore(path string) *FileSnapshotStore {
+	return &FileSnapshotStore{path: path}
+}
+
+// Save writes the snapshot to a temporary file and swaps it in place
+// so a crash never leaves a partial snapshot behind.
+func (f *FileSnapshotStore) Save(snap *Snapshot) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	if err := gob.NewEncoder(gz).Encode(snap); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// Load reads the snapshot back from disk
+func (f *FileSnapshotStore) Load() (*Snapshot, error) {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	snap := &Snapshot{}
+	if err := gob.NewDecoder(gz).Decode(snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// SetSnapshotStore checkpoints state to a store other than the configured file. Must be called before Run.
+func (s *MarketWatch) SetSnapshotStore(store SnapshotStore) {
+	s.snapshots = store
+}
+
+// takeSnapshot copies the current state out of the stores
+func (s *MarketWatch) takeSnapshot() *Snapshot {
+	snap := &Snapshot{
+		Time:      time.Now().UTC(),
+		Markets:   make(map[int64][]Order),
+		Contracts: make(map[int64][]Contract),
+	}
+
+	s.mmutex.RLock()
+	for l, r := range s.market {
+		orders := []Order{}
+		r.Range(func(k, v interface{}) bool {
+			orders = append(orders, v.(Order))
+			return true
+		})
+		snap.Markets[l] = orders
+	}
+	s.mmutex.RUnlock()
+
+	s.cmutex.RLock()
+	for l, r := range s.contracts {
+		contracts := []Contract{}
+		r.Range(func(k, v interface{}) bool {
+			contracts = append(contracts, v.(Contract))
+			return true
+		})
+		snap.Contracts[l] = contracts
+	}
+	s.cmutex.RUnlock()
+
+	s.smutex.RLock()
+	for l := range s.structures {
+		snap.Structures = append(snap.Structures, l)
+	}
+	s.smutex.RUnlock()
+
+	return snap
+}
+
+// restoreSnapshot loads state from the store so the first poll only reports real changes
+func (s *MarketWatch) restoreSnapshot() {
+	snap, err := s.snapshots.Load()
+	if err != nil {
+		log.Printf("failed to load snapshot: %v\n", err)
+		return
+	}
+	if snap == nil {
+		return
+	}
+
+	// Locations no longer in scope are dropped, as nothing would ever expire them
+	structures := make(map[int64]bool)
+	for _, l := range snap.Structures {
+		structures[l] = true
+		if s.config.Scope.allowMarket(l, true) {
+			s.createStructureState(l)
+		}
+	}
+	numOrders, numContracts := 0, 0
+	for l, orders := range snap.Markets {
+		if !s.config.Scope.allowMarket(l, structures[l]) {
+			continue
+		}
+		s.createMarketStore(l)
+		sMap := s.getMarketStore(l)
+		book := make([]esi.GetMarketsRegionIdOrders200Ok, 0, len(orders))
+		for _, o := range orders {
+			sMap.Store(o.Order.OrderId, o)
+			book = append(book, o.Order)
+		}
+		s.books.apply(s.regionForLocation(l), book, nil, nil)
+		numOrders += len(orders)
+	}
+	for l, contracts := range snap.Contracts {
+		if !s.config.Scope.allowContracts(l) {
+			continue
+		}
+		s.createContractStore(l)
+		sMap := s.getContractStore(l)
+		for _, c := range contracts {
+			sMap.Store(c.Contract.Contract.ContractId, c)
+		}
+		numContracts += len(contracts)
+	}
+	log.Printf("restored %d orders and %d contracts from %s snapshot\n", numOrders, numContracts, snap.Time)
+}
+
+// saveSnapshot checkpoints the current state
+func (s *MarketWatch) saveSnapshot() {
+	start := time.Now()
+	if err := s.snapshots.Save(s.takeSnapshot()); err != nil {
+		log.Printf("failed to save snapshot: %v\n", err)
+		return
+	}
+	log.Printf("saved snapshot in %s\n", time.Since(start))
+}
+
+// runSnapshots checkpoints state until shutdown
+func (s *MarketWatch) runSnapshots(ctx context.Context) {
+	for sleep(ctx, s.config.SnapshotInterval) {
+		s.saveSnapshot()
+	}
+}
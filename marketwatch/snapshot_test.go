@@ -0,0 +1,188 @@
+package marketwatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/antihax/goesi/esi"
+	"github.com/stretchr/testify/assert"
+)
+
+// testSnapshot has orders in a region and a structure, and a contract in the region
+func testSnapshot(touched time.Time) *Snapshot {
+	const (
+		region    = int64(10000002)
+		structure = int64(1022734985679)
+	)
+	structureOrder := testOrder(2, 10, 6)
+	structureOrder.LocationId = structure
+	return &Snapshot{
+		Time: touched,
+		Markets: map[int64][]Order{
+			region:    {{Touched: touched, Order: testOrder(1, 100, 5)}},
+			structure: {{Touched: touched, Order: structureOrder}},
+		},
+		Contracts: map[int64][]Contract{
+			region: {{Touched: touched, Contract: FullContract{
+				Contract: esi.GetContractsPublicRegionId200Ok{ContractId: 200, Type_: "item_exchange", Price: 5000, StartLocationId: 60003760, DateExpired: touched.Add(48 * time.Hour)},
+				Items:    []esi.GetContractsPublicItemsContractId200Ok{{RecordId: 1, TypeId: 34, Quantity: 1000, IsIncluded: true}},
+			}}},
+		},
+		Structures: []int64{structure},
+	}
+}
+
+func TestFileSnapshotStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileSnapshotStore(filepath.Join(dir, "snapshot.gob.gz"))
+
+	// Nothing saved yet
+	snap, err := store.Load()
+	assert.Nil(t, err)
+	assert.Nil(t, snap)
+
+	touched := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	want := testSnapshot(touched)
+	assert.Nil(t, store.Save(want))
+	snap, err = store.Load()
+	if assert.Nil(t, err) {
+		assert.Equal(t, want, snap)
+	}
+
+	// Saving again swaps the file in place without leaving temporary files behind
+	want.Markets[10000002][0].Order.VolumeRemain = 50
+	assert.Nil(t, store.Save(want))
+	snap, err = store.Load()
+	if assert.Nil(t, err) {
+		assert.Equal(t, int32(50), snap.Markets[10000002][0].Order.VolumeRemain)
+	}
+	files, _ := os.ReadDir(dir)
+	if assert.Len(t, files, 1) {
+		assert.Equal(t, "snapshot.gob.gz", files[0].Name())
+	}
+
+	// A save that cannot be swapped in cleans up after itself
+	blocked := filepath.Join(dir, "blocked")
+	assert.Nil(t, os.MkdirAll(filepath.Join(blocked, "full"), 0755))
+	assert.NotNil(t, NewFileSnapshotStore(blocked).Save(want))
+	files, _ = os.ReadDir(dir)
+	assert.Len(t, files, 2)
+
+	// Corrupt files are an error
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "snapshot.gob.gz"), []byte("not a snapshot"), 0644))
+	_, err = store.Load()
+	assert.NotNil(t, err)
+}
+
+// memorySnapshotStore keeps the snapshot in memory
+type memorySnapshotStore struct {
+	snap *Snapshot
+}
+
+func (m *memorySnapshotStore) Save(snap *Snapshot) error {
+	m.snap = snap
+	return nil
+}
+
+func (m *memorySnapshotStore) Load() (*Snapshot, error) {
+	return m.snap, nil
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	const (
+		region    = int64(10000002)
+		structure = int64(1022734985679)
+	)
+	touched := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	store := &memorySnapshotStore{snap: testSnapshot(touched)}
+
+	mw, err := NewMarketWatch(DefaultConfig())
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	mw.SetSnapshotStore(store)
+	mw.restoreSnapshot()
+
+	// The stores, structures and books are back
+	assert.NotNil(t, mw.getStructureState(structure))
+	assert.Nil(t, mw.getStructureState(region))
+	tops := mw.books.tops()
+	assert.Len(t, tops[region], 1)
+	assert.Len(t, tops[0], 1)
+
+	// with when each order and contract was last seen
+	v, ok := mw.getMarketStore(region).Load(int64(1))
+	if assert.True(t, ok) {
+		assert.Equal(t, touched, v.(Order).Touched)
+	}
+	v, ok = mw.getContractStore(region).Load(int32(200))
+	if assert.True(t, ok) {
+		assert.Equal(t, touched, v.(Contract).Touched)
+	}
+
+	// The first poll only reports real changes
+	change, isNew := mw.storeData(region, Order{Touched: time.Now(), Order: testOrder(1, 80, 5)})
+	assert.False(t, isNew)
+	assert.True(t, change.Changed)
+	assert.Equal(t, int32(20), change.VolumeChange)
+
+	// and expires what it no longer sees
+	assert.Len(t, mw.expireOrders(structure, touched), 0)
+	deletions := mw.expireOrders(structure, time.Now())
+	if assert.Len(t, deletions, 1) {
+		assert.Equal(t, int64(2), deletions[0].OrderID)
+	}
+	contracts := mw.expireContracts(region, time.Now())
+	if assert.Len(t, contracts, 1) {
+		assert.False(t, contracts[0].Expired)
+	}
+
+	// Saving writes the current state back
+	mw.saveSnapshot()
+	assert.Len(t, store.snap.Markets[region], 1)
+	assert.Len(t, store.snap.Markets[structure], 0)
+	assert.Len(t, store.snap.Contracts[region], 0)
+	assert.Equal(t, []int64{structure}, store.snap.Structures)
+	assert.Equal(t, int32(80), store.snap.Markets[region][0].Order.VolumeRemain)
+}
+
+func TestRestoreSnapshotScope(t *testing.T) {
+	const (
+		region    = int64(10000002)
+		structure = int64(1022734985679)
+	)
+	restore := func(scope Scope) *Snapshot {
+		config := DefaultConfig()
+		config.Scope = scope
+		mw, err := NewMarketWatch(config)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		mw.SetSnapshotStore(&memorySnapshotStore{snap: testSnapshot(time.Now().UTC())})
+		mw.restoreSnapshot()
+		return mw.Snapshot()
+	}
+
+	// Locations that are no longer polled are dropped
+	snap := restore(Scope{ExcludeRegions: []int64{region}})
+	assert.Nil(t, snap.Markets[region])
+	assert.Nil(t, snap.Contracts[region])
+	assert.Len(t, snap.Markets[structure], 1)
+	assert.Equal(t, []int64{structure}, snap.Structures)
+
+	snap = restore(Scope{Structures: []int64{1}})
+	assert.Len(t, snap.Markets[region], 1)
+	assert.Nil(t, snap.Markets[structure])
+	assert.Nil(t, snap.Structures)
+
+	snap = restore(Scope{DisableMarkets: true})
+	assert.Len(t, snap.Markets, 0)
+	assert.Nil(t, snap.Structures)
+	assert.Len(t, snap.Contracts[region], 1)
+
+	snap = restore(Scope{DisableContracts: true})
+	assert.Len(t, snap.Markets, 2)
+	assert.Len(t, snap.Contracts, 0)
+}
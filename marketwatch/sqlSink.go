@@ -0,0 +1,416 @@
+package marketwatch
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/antihax/goesi/esi"
+)
+
+// sqlDialect is what differs between the databases
+type sqlDialect struct {
+	// column type for times
+	timestamp string
+
+	// placeholders are $1, $2... rather than ?
+	numbered bool
+}
+
+// sqlDialects by driver name
+var sqlDialects = map[string]sqlDialect{
+	"postgres": {timestamp: "TIMESTAMPTZ", numbered: true},
+	"sqlite3":  {timestamp: "TIMESTAMP"},
+}
+
+// sqlSchema creates the tables, with TIMESTAMP replaced for the dialect
+var sqlSchema = []string{
+	`CREATE TABLE IF NOT EXISTS orders (
+		order_id BIGINT NOT NULL PRIMARY KEY,
+		region_id BIGINT NOT NULL,
+		location_id BIGINT NOT NULL,
+		system_id INTEGER NOT NULL,
+		type_id INTEGER NOT NULL,
+		is_buy_order BOOLEAN NOT NULL,
+		price DOUBLE PRECISION NOT NULL,
+		volume_remain INTEGER NOT NULL,
+		volume_total INTEGER NOT NULL,
+		min_volume INTEGER NOT NULL,
+		"range" TEXT NOT NULL,
+		duration INTEGER NOT NULL,
+		issued TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		deleted_at TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS orders_type_location ON orders (type_id, location_id)`,
+	`CREATE TABLE IF NOT EXISTS contracts (
+		contract_id BIGINT NOT NULL PRIMARY KEY,
+		region_id BIGINT NOT NULL,
+		type TEXT NOT NULL,
+		title TEXT NOT NULL,
+		issuer_id INTEGER NOT NULL,
+		issuer_corporation_id INTEGER NOT NULL,
+		for_corporation BOOLEAN NOT NULL,
+		start_location_id BIGINT NOT NULL,
+		end_location_id BIGINT NOT NULL,
+		price DOUBLE PRECISION NOT NULL,
+		buyout DOUBLE PRECISION NOT NULL,
+		reward DOUBLE PRECISION NOT NULL,
+		collateral DOUBLE PRECISION NOT NULL,
+		volume DOUBLE PRECISION NOT NULL,
+		days_to_complete INTEGER NOT NULL,
+		date_issued TIMESTAMP NOT NULL,
+		date_expired TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		deleted_at TIMESTAMP,
+		expired BOOLEAN NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS contract_items (
+		contract_id BIGINT NOT NULL,
+		record_id BIGINT NOT NULL,
+		item_id BIGINT NOT NULL,
+		type_id INTEGER NOT NULL,
+		quantity INTEGER NOT NULL,
+		is_included BOOLEAN NOT NULL,
+		is_blueprint_copy BOOLEAN NOT NULL,
+		material_efficiency INTEGER NOT NULL,
+		time_efficiency INTEGER NOT NULL,
+		runs INTEGER NOT NULL,
+		PRIMARY KEY (contract_id, record_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS contract_bids (
+		contract_id BIGINT NOT NULL,
+		bid_id INTEGER NOT NULL,
+		amount DOUBLE PRECISION NOT NULL,
+		date_bid TIMESTAMP NOT NULL,
+		PRIMARY KEY (contract_id, bid_id)
+	)`,
+}
+
+// Statements, written with ? placeholders. Orders belong to the region poll
+// that sees them, and structure polls only touch those no region has.
+const (
+	sqlUpsertOrder = `INSERT INTO orders (order_id, region_id, location_id, system_id, type_id,
+		is_buy_order, price, volume_remain, volume_total, min_volume, "range", duration, issued, updated_at, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)
+		ON CONFLICT (order_id) DO UPDATE SET
+		region_id = excluded.region_id, location_id = excluded.location_id, system_id = excluded.system_id,
+		type_id = excluded.type_id, is_buy_order = excluded.is_buy_order, price = excluded.price,
+		volume_remain = excluded.volume_remain, volume_total = excluded.volume_total,
+		min_volume = excluded.min_volume, "range" = excluded."range", duration = excluded.duration,
+		issued = excluded.issued, updated_at = excluded.updated_at, deleted_at = NULL
+		WHERE orders.region_id = 0 OR excluded.region_id <> 0`
+	sqlChangeOrder = `UPDATE orders SET price = ?, volume_remain = ?, duration = ?, issued = ?, updated_at = ?
+		WHERE region_id = ? AND order_id = ?`
+	sqlDeleteOrder     = `DELETE FROM orders WHERE region_id = ? AND order_id = ?`
+	sqlSoftDeleteOrder = `UPDATE orders SET deleted_at = ?, updated_at = ?
+		WHERE region_id = ? AND order_id = ? AND deleted_at IS NULL`
+
+	sqlUpsertContract = `INSERT INTO contracts (contract_id, region_id, type, title, issuer_id, issuer_corporation_id,
+		for_corporation, start_location_id, end_location_id, price, buyout, reward, collateral, volume,
+		days_to_complete, date_issued, date_expired, updated_at, deleted_at, expired)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, ?)
+		ON CONFLICT (contract_id) DO UPDATE SET
+		region_id = excluded.region_id, type = excluded.type, title = excluded.title,
+		issuer_id = excluded.issuer_id, issuer_corporation_id = excluded.issuer_corporation_id,
+		for_corporation = excluded.for_corporation, start_location_id = excluded.start_location_id,
+		end_location_id = excluded.end_location_id, price = excluded.price, buyout = excluded.buyout,
+		reward = excluded.reward, collateral = excluded.collateral, volume = excluded.volume,
+		days_to_complete = excluded.days_to_complete, date_issued = excluded.date_issued,
+		date_expired = excluded.date_expired, updated_at = excluded.updated_at, deleted_at = NULL,
+		expired = excluded.expired`
+	sqlUpsertItem = `INSERT INTO contract_items (contract_id, record_id, item_id, type_id, quantity,
+		is_included, is_blueprint_copy, material_efficiency, time_efficiency, runs)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (contract_id, record_id) DO NOTHING`
+	sqlUpsertBid = `INSERT INTO contract_bids (contract_id, bid_id, amount, date_bid) VALUES (?, ?, ?, ?)
+		ON CONFLICT (contract_id, bid_id) DO NOTHING`
+	sqlChangeContract     = `UPDATE contracts SET price = ?, updated_at = ? WHERE contract_id = ?`
+	sqlSoftDeleteContract = `UPDATE contracts SET deleted_at = ?, expired = ?, updated_at = ?
+		WHERE contract_id = ? AND deleted_at IS NULL`
+)
+
+// sqlDeleteContract removes a contract and its children
+var sqlDeleteContract = []string{
+	`DELETE FROM contract_bids WHERE contract_id = ?`,
+	`DELETE FROM contract_items WHERE contract_id = ?`,
+	`DELETE FROM contracts WHERE contract_id = ?`,
+}
+
+// SQLSink keeps tables of orders and contracts in step with the service, as a
+// PostgreSQL or SQLite database that can be queried directly. Orders in public
+// structures are seen by both the region and the structure poll, and are kept
+// once with the region's.
+//
+// Each poll is applied in a single transaction once all its events are in, so
+// the database never holds part of a poll. A poll that fails to apply leaves
+// the tables as they were, and they are rebuilt from the service's state.
+type SQLSink struct {
+	db         *sql.DB
+	dialect    sqlDialect
+	softDelete bool
+
+	// events of polls that are still publishing
+	pending map[sqlPoll][]Event
+}
+
+// sqlPoll is a Poll that can be used as a map key
+type sqlPoll struct {
+	channel  string
+	regionID int64
+	polledAt int64
+}
+
+// NewSQLSink mirrors to a database opened with a postgres or sqlite3 driver.
+// The tables are created if missing. With softDelete, orders and contracts that
+// are gone have deleted_at set rather than being removed.
+func NewSQLSink(db *sql.DB, driver string, softDelete bool) (*SQLSink, error) {
+	dialect, ok := sqlDialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("no sql dialect for driver %q", driver)
+	}
+	return &SQLSink{
+		db:         db,
+		dialect:    dialect,
+		softDelete: softDelete,
+		pending:    make(map[sqlPoll][]Event),
+	}, nil
+}
+
+// Write holds order and contract events until their poll finishes
+func (q *SQLSink) Write(e Event) error {
+	switch e.(type) {
+	case OrderAdded, OrderChanged, OrderDeleted, ContractAdded, ContractChanged, ContractDeleted:
+		key := sqlPoll{e.channel(), e.Region(), e.PollTime().UnixNano()}
+		q.pending[key] = append(q.pending[key], e)
+	}
+	return nil
+}
+
+// PollFinished applies a poll's events in one transaction
+func (q *SQLSink) PollFinished(p Poll) error {
+	key := sqlPoll{p.Channel, p.RegionID, p.PolledAt.UnixNano()}
+	events := q.pending[key]
+	delete(q.pending, key)
+	if len(events) == 0 {
+		return nil
+	}
+
+	return q.transaction(func(tx *sqlTx) error {
+		for _, e := range events {
+			if err := tx.apply(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Reset replaces the tables with the snapshot, creating them if needed
+func (q *SQLSink) Reset(snap *Snapshot) error {
+	return q.transaction(func(tx *sqlTx) error {
+		for _, stmt := range sqlSchema {
+			if _, err := tx.Exec(strings.Replace(stmt, "TIMESTAMP", q.dialect.timestamp, -1)); err != nil {
+				return err
+			}
+		}
+
+		if q.softDelete {
+			for _, table := range []string{"orders", "contracts"} {
+				if err := tx.exec("UPDATE "+table+" SET deleted_at = ? WHERE deleted_at IS NULL", snap.Time); err != nil {
+					return err
+				}
+			}
+		} else {
+			for _, table := range []string{"contract_bids", "contract_items", "contracts", "orders"} {
+				if err := tx.exec("DELETE FROM " + table); err != nil {
+					return err
+				}
+			}
+		}
+
+		structures := make(map[int64]bool)
+		for _, id := range snap.Structures {
+			structures[id] = true
+		}
+		for marketID, orders := range snap.Markets {
+			regionID := marketID
+			if structures[marketID] {
+				regionID = 0
+			}
+			for _, o := range orders {
+				if err := tx.upsertOrder(regionID, o); err != nil {
+					return err
+				}
+			}
+		}
+		for regionID, contracts := range snap.Contracts {
+			for _, c := range contracts {
+				if err := tx.upsertContract(regionID, c.Touched, c.Contract); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Close drops polls that never finished and closes the database
+func (q *SQLSink) Close() error {
+	q.pending = make(map[sqlPoll][]Event)
+	return q.db.Close()
+}
+
+// transaction runs f, committing if it succeeds
+func (q *SQLSink) transaction(f func(*sqlTx) error) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := f(&sqlTx{Tx: tx, sink: q, stmts: make(map[string]*sql.Stmt)}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// rebind swaps the ? placeholders for the dialect's
+func (q *SQLSink) rebind(query string) string {
+	if !q.dialect.numbered {
+		return query
+	}
+	b := strings.Builder{}
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// sqlTx applies changes within a transaction, preparing each statement once
+type sqlTx struct {
+	*sql.Tx
+	sink  *SQLSink
+	stmts map[string]*sql.Stmt
+}
+
+// exec runs a statement written with ? placeholders
+func (tx *sqlTx) exec(query string, args ...interface{}) error {
+	stmt, ok := tx.stmts[query]
+	if !ok {
+		var err error
+		stmt, err = tx.Prepare(tx.sink.rebind(query))
+		if err != nil {
+			return err
+		}
+		tx.stmts[query] = stmt
+	}
+	_, err := stmt.Exec(args...)
+	return err
+}
+
+// apply an event to the tables
+func (tx *sqlTx) apply(e Event) error {
+	switch e := e.(type) {
+	case OrderAdded:
+		for _, o := range e.Orders {
+			if err := tx.upsertOrder(e.RegionID, Order{Touched: e.PolledAt, Order: o}); err != nil {
+				return err
+			}
+		}
+	case OrderChanged:
+		for _, c := range e.Changes {
+			if err := tx.exec(sqlChangeOrder, c.Price, c.VolumeRemain, c.Duration, c.Issued, e.PolledAt,
+				e.RegionID, c.OrderID); err != nil {
+				return err
+			}
+		}
+	case OrderDeleted:
+		for _, c := range e.Deletions {
+			var err error
+			if tx.sink.softDelete {
+				err = tx.exec(sqlSoftDeleteOrder, e.PolledAt, e.PolledAt, e.RegionID, c.OrderID)
+			} else {
+				err = tx.exec(sqlDeleteOrder, e.RegionID, c.OrderID)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case ContractAdded:
+		for _, c := range e.Contracts {
+			if err := tx.upsertContract(e.RegionID, e.PolledAt, c); err != nil {
+				return err
+			}
+		}
+	case ContractChanged:
+		for _, c := range e.Changes {
+			if err := tx.exec(sqlChangeContract, c.Price, e.PolledAt, c.ContractId); err != nil {
+				return err
+			}
+			if err := tx.upsertBids(c.ContractId, c.Bids); err != nil {
+				return err
+			}
+		}
+	case ContractDeleted:
+		for _, c := range e.Deletions {
+			if err := tx.deleteContract(c, e.PolledAt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// upsertOrder adds or replaces an order, undeleting it
+func (tx *sqlTx) upsertOrder(regionID int64, o Order) error {
+	return tx.exec(sqlUpsertOrder, o.Order.OrderId, regionID, o.Order.LocationId, o.Order.SystemId,
+		o.Order.TypeId, o.Order.IsBuyOrder, o.Order.Price, o.Order.VolumeRemain, o.Order.VolumeTotal,
+		o.Order.MinVolume, o.Order.Range_, o.Order.Duration, o.Order.Issued, o.Touched)
+}
+
+// upsertContract adds or replaces a contract with its items and bids
+func (tx *sqlTx) upsertContract(regionID int64, updated time.Time, c FullContract) error {
+	k := c.Contract
+	if err := tx.exec(sqlUpsertContract, k.ContractId, regionID, k.Type_, k.Title, k.IssuerId,
+		k.IssuerCorporationId, k.ForCorporation, k.StartLocationId, k.EndLocationId, k.Price, k.Buyout,
+		k.Reward, k.Collateral, k.Volume, k.DaysToComplete, k.DateIssued, k.DateExpired, updated, false); err != nil {
+		return err
+	}
+	for _, i := range c.Items {
+		if err := tx.exec(sqlUpsertItem, k.ContractId, i.RecordId, i.ItemId, i.TypeId, i.Quantity,
+			i.IsIncluded, i.IsBlueprintCopy, i.MaterialEfficiency, i.TimeEfficiency, i.Runs); err != nil {
+			return err
+		}
+	}
+	return tx.upsertBids(k.ContractId, c.Bids)
+}
+
+// upsertBids adds any bids not already recorded, bids never change
+func (tx *sqlTx) upsertBids(contractID int32, bids []esi.GetContractsPublicBidsContractId200Ok) error {
+	for _, b := range bids {
+		if err := tx.exec(sqlUpsertBid, contractID, b.BidId, float64(b.Amount), b.DateBid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteContract removes a contract and its children, or marks it deleted
+func (tx *sqlTx) deleteContract(c ContractChange, polled time.Time) error {
+	if tx.sink.softDelete {
+		return tx.exec(sqlSoftDeleteContract, polled, c.Expired, polled, c.ContractId)
+	}
+	for _, query := range sqlDeleteContract {
+		if err := tx.exec(query, c.ContractId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package marketwatch
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/antihax/goesi/esi"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+// sqliteDSN is a database file in a temporary directory
+func sqliteDSN(t *testing.T) string {
+	return "file:" + filepath.Join(t.TempDir(), "marketwatch.db") + "?_busy_timeout=5000"
+}
+
+// queryInt runs a query returning a single number
+func queryInt(t *testing.T, db *sql.DB, query string, args ...interface{}) int64 {
+	var n int64
+	assert.Nil(t, db.QueryRow(query, args...).Scan(&n))
+	return n
+}
+
+func TestSQLSink(t *testing.T) {
+	const (
+		region    = int64(10000002)
+		structure = int64(1022734985679)
+	)
+	polled := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	expires := polled.Add(48 * time.Hour)
+
+	structureOrder := testOrder(2, 10, 6)
+	structureOrder.LocationId = structure
+	auction := FullContract{
+		Contract: esi.GetContractsPublicRegionId200Ok{ContractId: 200, Type_: "auction", Price: 5000, StartLocationId: 60003760, DateExpired: expires},
+		Items:    []esi.GetContractsPublicItemsContractId200Ok{{RecordId: 1, TypeId: 34, Quantity: 1000, IsIncluded: true}},
+		Bids:     []esi.GetContractsPublicBidsContractId200Ok{{BidId: 1, Amount: 5000, DateBid: polled}},
+	}
+	snap := &Snapshot{
+		Time: polled,
+		Markets: map[int64][]Order{
+			region:    {{Touched: polled, Order: testOrder(1, 100, 5)}},
+			structure: {{Touched: polled, Order: structureOrder}},
+		},
+		Contracts:  map[int64][]Contract{region: {{Touched: polled, Contract: auction}}},
+		Structures: []int64{structure},
+	}
+
+	for _, soft := range []bool{false, true} {
+		dsn := sqliteDSN(t)
+		db, _ := sql.Open("sqlite3", dsn)
+		sink, err := NewSQLSink(db, "sqlite3", soft)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		check, _ := sql.Open("sqlite3", dsn)
+		defer check.Close()
+
+		// Something left from before a restart is replaced
+		assert.Nil(t, sink.Reset(&Snapshot{Time: polled, Markets: map[int64][]Order{region: {{Touched: polled, Order: testOrder(9, 1, 1)}}}}))
+		assert.Nil(t, sink.Reset(snap))
+		assert.Equal(t, int64(2), queryInt(t, check, "SELECT COUNT(*) FROM orders WHERE deleted_at IS NULL"))
+		assert.Equal(t, region, queryInt(t, check, "SELECT region_id FROM orders WHERE order_id = 1"))
+		assert.Equal(t, int64(0), queryInt(t, check, "SELECT region_id FROM orders WHERE order_id = 2"))
+		assert.Equal(t, int64(1), queryInt(t, check, "SELECT COUNT(*) FROM contract_items WHERE contract_id = 200"))
+		assert.Equal(t, int64(1), queryInt(t, check, "SELECT COUNT(*) FROM contract_bids WHERE contract_id = 200"))
+
+		// Nothing is applied until the poll finishes
+		polled = polled.Add(5 * time.Minute)
+		poll := Poll{Channel: "market", RegionID: region, PolledAt: polled}
+		assert.Nil(t, sink.Write(OrderAdded{RegionID: region, Orders: []esi.GetMarketsRegionIdOrders200Ok{testOrder(3, 50, 7)}, PolledAt: polled}))
+		assert.Nil(t, sink.Write(OrderChanged{RegionID: region, Changes: []OrderChange{{OrderID: 1, LocationId: 60003760, VolumeRemain: 80, Price: 5, Duration: 90}}, PolledAt: polled}))
+		assert.Nil(t, sink.Write(BookChanged{RegionID: region, PolledAt: polled}))
+		assert.Equal(t, int64(2), queryInt(t, check, "SELECT COUNT(*) FROM orders WHERE deleted_at IS NULL"))
+		assert.Nil(t, sink.PollFinished(Poll{Channel: "market", RegionID: region, PolledAt: polled.Add(-time.Minute)}))
+		assert.Equal(t, int64(2), queryInt(t, check, "SELECT COUNT(*) FROM orders WHERE deleted_at IS NULL"))
+		assert.Nil(t, sink.PollFinished(poll))
+		assert.Equal(t, int64(3), queryInt(t, check, "SELECT COUNT(*) FROM orders WHERE deleted_at IS NULL"))
+		assert.Equal(t, int64(80), queryInt(t, check, "SELECT volume_remain FROM orders WHERE order_id = 1"))
+
+		// Structure orders are kept apart from the region's
+		polled = polled.Add(5 * time.Minute)
+		assert.Nil(t, sink.Write(OrderDeleted{Deletions: []OrderChange{{OrderID: 2, LocationId: structure}}, PolledAt: polled}))
+		assert.Nil(t, sink.Write(OrderDeleted{RegionID: region, Deletions: []OrderChange{{OrderID: 1, LocationId: 60003760}}, PolledAt: polled}))
+		assert.Nil(t, sink.PollFinished(Poll{Channel: "market", PolledAt: polled}))
+		assert.Equal(t, int64(2), queryInt(t, check, "SELECT COUNT(*) FROM orders WHERE deleted_at IS NULL"))
+		assert.Nil(t, sink.PollFinished(Poll{Channel: "market", RegionID: region, PolledAt: polled}))
+		assert.Equal(t, int64(1), queryInt(t, check, "SELECT COUNT(*) FROM orders WHERE deleted_at IS NULL"))
+
+		// Orders in public structures seen by both polls are kept once, with the region's
+		public := testOrder(4, 10, 6)
+		public.LocationId = structure
+		seen, gone := polled.Add(time.Minute), polled.Add(2*time.Minute)
+		assert.Nil(t, sink.Write(OrderAdded{Orders: []esi.GetMarketsRegionIdOrders200Ok{public}, PolledAt: seen}))
+		assert.Nil(t, sink.PollFinished(Poll{Channel: "market", PolledAt: seen}))
+		assert.Nil(t, sink.Write(OrderAdded{RegionID: region, Orders: []esi.GetMarketsRegionIdOrders200Ok{public}, PolledAt: seen}))
+		assert.Nil(t, sink.PollFinished(Poll{Channel: "market", RegionID: region, PolledAt: seen}))
+		assert.Nil(t, sink.Write(OrderAdded{Orders: []esi.GetMarketsRegionIdOrders200Ok{public}, PolledAt: seen.Add(time.Second)}))
+		assert.Nil(t, sink.PollFinished(Poll{Channel: "market", PolledAt: seen.Add(time.Second)}))
+		assert.Equal(t, int64(1), queryInt(t, check, "SELECT COUNT(*) FROM orders WHERE order_id = 4"))
+		assert.Equal(t, region, queryInt(t, check, "SELECT region_id FROM orders WHERE order_id = 4"))
+		assert.Equal(t, int64(10), queryInt(t, check, "SELECT SUM(volume_remain) FROM orders WHERE type_id = 34 AND location_id = ? AND deleted_at IS NULL", structure))
+
+		// and only the region poll removes them
+		assert.Nil(t, sink.Write(OrderDeleted{Deletions: []OrderChange{{OrderID: 4, LocationId: structure}}, PolledAt: gone}))
+		assert.Nil(t, sink.PollFinished(Poll{Channel: "market", PolledAt: gone}))
+		assert.Equal(t, int64(2), queryInt(t, check, "SELECT COUNT(*) FROM orders WHERE deleted_at IS NULL"))
+		assert.Nil(t, sink.Write(OrderDeleted{RegionID: region, Deletions: []OrderChange{{OrderID: 4, LocationId: structure}}, PolledAt: gone}))
+		assert.Nil(t, sink.PollFinished(Poll{Channel: "market", RegionID: region, PolledAt: gone}))
+		assert.Equal(t, int64(1), queryInt(t, check, "SELECT COUNT(*) FROM orders WHERE deleted_at IS NULL"))
+
+		// Contracts change and go with their items and bids
+		bids := append(auction.Bids, esi.GetContractsPublicBidsContractId200Ok{BidId: 2, Amount: 6000, DateBid: polled})
+		assert.Nil(t, sink.Write(ContractChanged{RegionID: region, Changes: []ContractChange{{ContractId: 200, Bids: bids, Price: 5000}}, PolledAt: polled}))
+		assert.Nil(t, sink.PollFinished(Poll{Channel: "contract", RegionID: region, PolledAt: polled}))
+		assert.Equal(t, int64(2), queryInt(t, check, "SELECT COUNT(*) FROM contract_bids WHERE contract_id = 200"))
+
+		polled = polled.Add(5 * time.Minute)
+		assert.Nil(t, sink.Write(ContractDeleted{RegionID: region, Deletions: []ContractChange{{ContractId: 200, Expired: true}}, PolledAt: polled}))
+		assert.Nil(t, sink.PollFinished(Poll{Channel: "contract", RegionID: region, PolledAt: polled}))
+		assert.Equal(t, int64(0), queryInt(t, check, "SELECT COUNT(*) FROM contracts WHERE deleted_at IS NULL"))
+
+		if soft {
+			var deleted time.Time
+			assert.Nil(t, check.QueryRow("SELECT deleted_at FROM orders WHERE order_id = 1").Scan(&deleted))
+			assert.True(t, polled.Add(-5*time.Minute).Equal(deleted))
+			assert.Equal(t, int64(1), queryInt(t, check, "SELECT COUNT(*) FROM contracts WHERE contract_id = 200 AND expired"))
+			assert.Equal(t, int64(2), queryInt(t, check, "SELECT COUNT(*) FROM contract_bids"))
+
+			// Orders that come back are undeleted
+			polled = polled.Add(5 * time.Minute)
+			assert.Nil(t, sink.Write(OrderAdded{RegionID: region, Orders: []esi.GetMarketsRegionIdOrders200Ok{testOrder(1, 80, 5)}, PolledAt: polled}))
+			assert.Nil(t, sink.PollFinished(Poll{Channel: "market", RegionID: region, PolledAt: polled}))
+			assert.Equal(t, int64(2), queryInt(t, check, "SELECT COUNT(*) FROM orders WHERE deleted_at IS NULL"))
+		} else {
+			assert.Equal(t, int64(1), queryInt(t, check, "SELECT COUNT(*) FROM orders"))
+			assert.Equal(t, int64(0), queryInt(t, check, "SELECT COUNT(*) FROM contract_items"))
+			assert.Equal(t, int64(0), queryInt(t, check, "SELECT COUNT(*) FROM contract_bids"))
+		}
+		assert.Nil(t, sink.Close())
+	}
+}
+
+// failingSink is a PollSink whose first poll fails
+type failingSink struct {
+	resets chan *Snapshot
+	failed bool
+}
+
+func (f *failingSink) Write(Event) error { return nil }
+func (f *failingSink) Close() error      { return nil }
+
+func (f *failingSink) Reset(snap *Snapshot) error {
+	f.resets <- snap
+	return nil
+}
+
+func (f *failingSink) PollFinished(Poll) error {
+	if !f.failed {
+		f.failed = true
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func TestSQLSinkPolls(t *testing.T) {
+	const region = int32(10000002)
+
+	f := newFakeESI()
+	defer f.Close()
+	f.setRegions(region)
+	f.setOrders(region, testOrder(1, 100, 5), testOrder(2, 50, 6))
+
+	dsn := sqliteDSN(t)
+	config := DefaultConfig()
+	config.Scope.DisableContracts = true
+	config.Sinks.SQL = SQLConfig{Driver: "sqlite3", DSN: dsn}
+	assert.Nil(t, config.Validate())
+	mw := newTestMarketWatch(t, f, config)
+	failing := &failingSink{resets: make(chan *Snapshot, 10)}
+	mw.AddSink(failing)
+	startMarketWatch(t, mw)
+
+	check, _ := sql.Open("sqlite3", dsn)
+	defer check.Close()
+	holds := func(query string) func() bool {
+		return func() bool {
+			ok := false
+			return check.QueryRow(query).Scan(&ok) == nil && ok
+		}
+	}
+	assert.Eventually(t, holds("SELECT COUNT(*) = 2 FROM orders"), 5*time.Second, 10*time.Millisecond)
+	f.setOrders(region, testOrder(1, 100, 5), testOrder(2, 20, 6))
+	assert.Eventually(t, holds("SELECT volume_remain = 20 FROM orders WHERE order_id = 2"), 5*time.Second, 10*time.Millisecond)
+	f.setOrders(region, testOrder(1, 100, 5))
+	assert.Eventually(t, holds("SELECT COUNT(*) = 1 FROM orders"), 5*time.Second, 10*time.Millisecond)
+
+	// Reset at the start, and again after the poll that failed
+	for i := 0; i < 2; i++ {
+		select {
+		case <-failing.resets:
+		case <-time.After(5 * time.Second):
+			t.Fatal("sink not reset")
+		}
+	}
+
+	// Unknown drivers are caught before starting
+	config.Sinks.SQL.Driver = "mysql"
+	assert.NotNil(t, config.Validate())
+	_, err := NewMarketWatch(config)
+	assert.NotNil(t, err)
+}
@@ -18,49 +18,69 @@ import (
 type Structure struct {
 	restart time.Time
 	running bool
+
+	// region the structure's orders were last seen in, if any
+	regionID int64
 }
 
-func (s *MarketWatch) getAuthContext() context.Context {
-	return context.WithValue(context.Background(), goesi.ContextOAuth2, *s.token)
+func (s *MarketWatch) getAuthContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, goesi.ContextOAuth2, *s.token)
 }
 
-func (s *MarketWatch) runStructures() {
-	for {
+func (s *MarketWatch) runStructures(ctx context.Context) {
+	for ctx.Err() == nil {
 		// Get all the structures and fire up workers for each
-		structures, res, err := s.esi.ESI.UniverseApi.GetUniverseStructures(s.getAuthContext(), nil)
+		structures, res, err := s.esi.ESI.UniverseApi.GetUniverseStructures(s.getAuthContext(ctx), nil)
 		if err != nil {
 			log.Println(err)
 			continue
 		}
 		for _, structure := range structures {
+			if !s.config.Scope.allowStructure(structure) {
+				continue
+			}
 			state := s.getStructureState(structure)
 			// Prebuild the maps
 			if state == nil {
 				s.createMarketStore(structure)
 				state = s.createStructureState(structure)
 			}
-			if state.running == false && time.Now().After(state.restart) {
-				time.Sleep(time.Second * 1)
-				state.running = true
-				go s.structureWorker(structure)
+			if s.claimStructure(state) {
+				if !sleep(ctx, time.Second*1) {
+					return
+				}
+				s.spawn(ctx, func(ctx context.Context) { s.structureWorker(ctx, structure) })
 			}
 		}
-		time.Sleep(timeUntilCacheExpires(res))
+		sleep(ctx, timeUntilCacheExpires(res))
 	}
 }
 
+// claimStructure marks a structure as running if it is due a worker
+func (s *MarketWatch) claimStructure(state *Structure) bool {
+	s.smutex.Lock()
+	defer s.smutex.Unlock()
+	if state.running || time.Now().Before(state.restart) {
+		return false
+	}
+	state.running = true
+	return true
+}
+
 func (s *MarketWatch) failStructure(structureID int64) {
 	state := s.getStructureState(structureID)
+	s.smutex.Lock()
+	defer s.smutex.Unlock()
 	state.restart = time.Now().Add(time.Hour * 12)
 	state.running = false
 }
 
-func (s *MarketWatch) structureWorker(structureID int64) {
+func (s *MarketWatch) structureWorker(ctx context.Context, structureID int64) {
 	// For totalization
 	wg := sync.WaitGroup{}
 
-	// Loop forever
-	for {
+	// Loop until shutdown
+	for ctx.Err() == nil {
 		start := time.Now()
 		numOrders := 0
 
@@ -69,7 +89,7 @@ func (s *MarketWatch) structureWorker(structureID int64) {
 		echan := make(chan error, 100000)
 
 		orders, res, err := s.esi.ESI.MarketApi.GetMarketsStructuresStructureId(
-			s.getAuthContext(), structureID, nil,
+			s.getAuthContext(ctx), structureID, nil,
 		)
 		if err != nil {
 			// If we do not have access, get out of the loop.
@@ -83,9 +103,9 @@ func (s *MarketWatch) structureWorker(structureID int64) {
 
 		rchan <- orders
 		duration := timeUntilCacheExpires(res)
-		if duration.Minutes() < 3 {
+		if duration < s.config.MinimumCacheWindow {
 			fmt.Printf("%d too close to window: waiting %s\n", structureID, duration.String())
-			time.Sleep(duration)
+			sleep(ctx, duration)
 			continue
 		}
 		// Figure out if there are more pages
@@ -102,7 +122,7 @@ func (s *MarketWatch) structureWorker(structureID int64) {
 				defer wg.Done() // release when done
 
 				orders, r, err := s.esi.ESI.MarketApi.GetMarketsStructuresStructureId(
-					context.Background(),
+					s.getAuthContext(ctx),
 					structureID,
 					&esi.GetMarketsStructuresStructureIdOpts{Page: optional.NewInt32(page)},
 				)
@@ -114,7 +134,7 @@ func (s *MarketWatch) structureWorker(structureID int64) {
 
 				// Are we too close to the end of the window?
 				duration := timeUntilCacheExpires(r)
-				if duration.Seconds() < 20 {
+				if duration < s.config.MinimumPageWindow {
 					echan <- errors.New("too close to end of window")
 					return
 				}
@@ -131,9 +151,18 @@ func (s *MarketWatch) structureWorker(structureID int64) {
 		close(rchan)
 		close(echan)
 
+		// Don't report a partial poll as deletions when shutting down
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Start over if any requests failed
+		failed := false
 		for err := range echan {
-			// Start over if any requests failed
 			log.Println(err)
+			failed = true
+		}
+		if failed {
 			continue
 		}
 
@@ -166,37 +195,23 @@ func (s *MarketWatch) structureWorker(structureID int64) {
 		).Observe(float64(time.Since(start).Nanoseconds()) / float64(time.Millisecond))
 
 		if len(newOrders) > 0 {
-			s.broadcast.Broadcast(
-				"market",
-				Message{
-					Action:  "addition",
-					Payload: newOrders,
-				},
-			)
+			s.publish(OrderAdded{Orders: newOrders, PolledAt: start})
 		}
 
 		if len(changes) > 0 {
-			s.broadcast.Broadcast(
-				"market",
-				Message{
-					Action:  "change",
-					Payload: changes,
-				},
-			)
+			s.publish(OrderChanged{Changes: changes, PolledAt: start})
 		}
 
 		if len(deletions) > 0 {
-			s.broadcast.Broadcast(
-				"market",
-				Message{
-					Action:  "deletion",
-					Payload: deletions,
-				},
-			)
+			s.publish(OrderDeleted{Deletions: deletions, PolledAt: start})
 		}
+		s.finishPoll("market", 0, start)
+
+		s.updateBooks(0, start, newOrders, changes, deletions)
+		s.recordTrades(0, start, changes, deletions)
 
 		// Sleep until the cache timer expires
-		time.Sleep(duration)
+		sleep(ctx, duration)
 	}
 }
 
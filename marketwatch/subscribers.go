@@ -0,0 +1,160 @@
+package marketwatch
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/antihax/eve-marketwatch/wsbroadcast"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Events buffered for each subscriber before it is dropped, or waited on if it waits
+const subscriberBuffer = 1024
+
+type subscriber struct {
+	filter *wsbroadcast.Filter
+	events chan Event
+	done   chan struct{}
+	stop   sync.Once
+
+	// also receives pollFinished after each poll's events
+	polls bool
+
+	// polling waits for it rather than dropping it when it falls behind
+	wait bool
+}
+
+// unsubscribe releases any publisher waiting on the subscriber, once
+func (sub *subscriber) unsubscribe() {
+	sub.stop.Do(func() { close(sub.done) })
+}
+
+// deliver an event, dropping the subscriber if it is too far behind and does not wait
+func (sub *subscriber) deliver(e Event) {
+	select {
+	case <-sub.done:
+		return
+	default:
+	}
+	if sub.wait {
+		select {
+		case sub.events <- e:
+		case <-sub.done:
+		}
+		return
+	}
+	select {
+	case sub.events <- e:
+	default:
+		log.Println("dropping subscriber: too far behind")
+		metricSubscribersDropped.Inc()
+		sub.unsubscribe()
+	}
+}
+
+// subscribers fan events out to everything listening in process
+type subscribers struct {
+	mutex sync.RWMutex
+	list  map[*subscriber]bool
+}
+
+// Subscribe returns events matching the filter until the context is cancelled,
+// when the channel is closed. A nil filter receives everything.
+//
+// Events are delivered in the order each poll found them. A subscriber that
+// falls 1024 events behind is dropped and its channel closed while the context
+// is still live, so read the channel promptly and subscribe again with a new
+// Snapshot if it happens. Subscribe before taking a Snapshot so no change is
+// missed in between.
+func (s *MarketWatch) Subscribe(ctx context.Context, filter *wsbroadcast.Filter) <-chan Event {
+	return s.subscribe(ctx, filter, false, false)
+}
+
+// subscribe optionally marking the end of each poll and waiting rather than dropping,
+// for sinks and the websocket hub which cannot miss anything
+func (s *MarketWatch) subscribe(ctx context.Context, filter *wsbroadcast.Filter, polls, wait bool) <-chan Event {
+	sub := &subscriber{
+		filter: filter,
+		events: make(chan Event, subscriberBuffer),
+		done:   make(chan struct{}),
+		polls:  polls,
+		wait:   wait,
+	}
+
+	s.subscribers.mutex.Lock()
+	if s.subscribers.list == nil {
+		s.subscribers.list = make(map[*subscriber]bool)
+	}
+	s.subscribers.list[sub] = true
+	s.subscribers.mutex.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-sub.done:
+		}
+		// Release any publisher waiting on us before taking the lock
+		sub.unsubscribe()
+		s.subscribers.mutex.Lock()
+		delete(s.subscribers.list, sub)
+		close(sub.events)
+		s.subscribers.mutex.Unlock()
+	}()
+
+	return sub.events
+}
+
+// Snapshot returns a copy of the current market and contract state
+func (s *MarketWatch) Snapshot() *Snapshot {
+	return s.takeSnapshot()
+}
+
+// publish an event to every subscriber whose filter it matches
+func (s *MarketWatch) publish(e Event) {
+	s.subscribers.mutex.RLock()
+	defer s.subscribers.mutex.RUnlock()
+
+	for sub := range s.subscribers.list {
+		if fe, ok := filterEvent(e, sub.filter); ok {
+			sub.deliver(fe)
+		}
+	}
+}
+
+// finishPoll tells the sinks that want to know that a poll has published everything it found
+func (s *MarketWatch) finishPoll(channel string, regionID int64, polled time.Time) {
+	s.subscribers.mutex.RLock()
+	defer s.subscribers.mutex.RUnlock()
+
+	e := pollFinished{Poll{Channel: channel, RegionID: regionID, PolledAt: polled}}
+	for sub := range s.subscribers.list {
+		if sub.polls {
+			sub.deliver(e)
+		}
+	}
+}
+
+// runBroadcast feeds events to the websocket hub
+func (s *MarketWatch) runBroadcast(events <-chan Event) {
+	for e := range events {
+		s.broadcast.Broadcast(e.channel(), eventMessage(e))
+	}
+}
+
+// Metrics
+var (
+	metricSubscribersDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "evemarketwatch",
+		Subsystem: "subscribers",
+		Name:      "dropped",
+		Help:      "Subscribers dropped for falling too far behind.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		metricSubscribersDropped,
+	)
+}
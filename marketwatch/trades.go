@@ -0,0 +1,166 @@
+package marketwatch
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// Orders removed before expiry with at most this fraction left are counted as filled
+	nearlyFilled = 0.1
+
+	// Number of trades kept in memory
+	maxTrades = 500000
+)
+
+// Trade is a transaction estimated from the change in an order
+type Trade struct {
+	OrderID    int64     `json:"order_id"`
+	LocationID int64     `json:"location_id"`
+	RegionID   int64     `json:"region_id,omitempty"`
+	TypeID     int32     `json:"type_id"`
+	Price      float64   `json:"price"`
+	Quantity   int32     `json:"quantity"`
+	Side       string    `json:"side"`
+	Filled     bool      `json:"filled,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// inferTrades turns volume decreases and filled orders into trades.
+// A sell order losing volume is a "buy" and a buy order losing volume is a "sell".
+func inferTrades(regionID int64, changes, deletions []OrderChange) []Trade {
+	trades := []Trade{}
+	for _, c := range changes {
+		if c.VolumeChange > 0 {
+			trades = append(trades, newTrade(regionID, c, false))
+		}
+	}
+
+	for _, c := range deletions {
+		// Expired orders simply fall off the market
+		if c.VolumeChange <= 0 || c.volumeTotal <= 0 ||
+			!c.TimeChanged.Before(c.Issued.Add(time.Duration(c.Duration)*24*time.Hour)) {
+			continue
+		}
+		// Anything with much left was likely cancelled
+		if float64(c.VolumeChange) > float64(c.volumeTotal)*nearlyFilled {
+			continue
+		}
+		trades = append(trades, newTrade(regionID, c, true))
+	}
+	return trades
+}
+
+func newTrade(regionID int64, c OrderChange, filled bool) Trade {
+	side := "buy"
+	if c.IsBuyOrder {
+		side = "sell"
+	}
+	return Trade{
+		OrderID:    c.OrderID,
+		LocationID: c.LocationId,
+		RegionID:   regionID,
+		TypeID:     c.TypeID,
+		Price:      c.Price,
+		Quantity:   c.VolumeChange,
+		Side:       side,
+		Filled:     filled,
+		Time:       c.TimeChanged,
+	}
+}
+
+// tradeLog keeps the most recent trades in order
+type tradeLog struct {
+	mutex  sync.RWMutex
+	trades []Trade
+}
+
+// add trades, dropping the oldest when full
+func (l *tradeLog) add(trades []Trade) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.trades = append(l.trades, trades...)
+	if over := len(l.trades) - maxTrades; over > 0 {
+		l.trades = append([]Trade(nil), l.trades[over:]...)
+	}
+}
+
+// find trades since a time matching the filter, up to limit newest trades
+func (l *tradeLog) find(match func(Trade) bool, since time.Time, limit int) []Trade {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	trades := []Trade{}
+	for i := len(l.trades) - 1; i >= 0 && len(trades) < limit; i-- {
+		t := l.trades[i]
+		if t.Time.Before(since) {
+			break
+		}
+		if match(t) {
+			trades = append(trades, t)
+		}
+	}
+
+	// oldest first
+	for i, j := 0, len(trades)-1; i < j; i, j = i+1, j-1 {
+		trades[i], trades[j] = trades[j], trades[i]
+	}
+	return trades
+}
+
+// recordTrades infers trades from a poll, stores them and broadcasts them
+func (s *MarketWatch) recordTrades(regionID int64, polled time.Time, changes, deletions []OrderChange) {
+	trades := s.structureTrades(regionID, inferTrades(regionID, changes, deletions))
+	if len(trades) == 0 {
+		return
+	}
+	s.trades.add(trades)
+	metricTrades.Add(float64(len(trades)))
+
+	s.publish(TradeInferred{RegionID: regionID, Trades: trades, PolledAt: polled})
+
+	s.broadcastCandles(polled, s.candles.add(trades))
+}
+
+// structureTrades counts trades in structures that are polled directly only
+// from their own poll, as their orders also show up in the region's poll.
+// The region they were seen in is kept for the structure's trades.
+func (s *MarketWatch) structureTrades(regionID int64, trades []Trade) []Trade {
+	s.smutex.Lock()
+	defer s.smutex.Unlock()
+	kept := trades[:0]
+	for _, t := range trades {
+		state := s.structures[t.LocationID]
+		switch {
+		case state == nil:
+			kept = append(kept, t)
+		case regionID != 0:
+			state.regionID = regionID
+			if !state.running {
+				kept = append(kept, t)
+			}
+		default:
+			t.RegionID = state.regionID
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// Metrics
+var (
+	metricTrades = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "evemarketwatch",
+		Subsystem: "trades",
+		Name:      "inferred",
+		Help:      "Count of trades inferred from order changes.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		metricTrades,
+	)
+}
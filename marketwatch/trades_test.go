@@ -0,0 +1,184 @@
+package marketwatch
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferTrades(t *testing.T) {
+	now := time.Now().UTC()
+	issued := now.Add(-time.Hour)
+
+	trades := inferTrades(10000002,
+		[]OrderChange{
+			// Sell order bought from
+			{OrderID: 1, LocationId: 60003760, TypeID: 34, Price: 5, VolumeChange: 10, VolumeRemain: 90, TimeChanged: now},
+			// Price change only
+			{OrderID: 2, LocationId: 60003760, TypeID: 34, Price: 4, VolumeRemain: 50, TimeChanged: now},
+		},
+		[]OrderChange{
+			// Buy order nearly filled then gone
+			{OrderID: 3, LocationId: 60003760, TypeID: 34, Price: 3, VolumeChange: 5, IsBuyOrder: true,
+				Issued: issued, Duration: 90, volumeTotal: 100, TimeChanged: now},
+			// Cancelled with most of it left
+			{OrderID: 4, LocationId: 60003760, TypeID: 34, Price: 3, VolumeChange: 80,
+				Issued: issued, Duration: 90, volumeTotal: 100, TimeChanged: now},
+			// Expired
+			{OrderID: 5, LocationId: 60003760, TypeID: 34, Price: 3, VolumeChange: 1,
+				Issued: now.Add(-91 * 24 * time.Hour), Duration: 90, volumeTotal: 100, TimeChanged: now},
+		},
+	)
+
+	assert.Len(t, trades, 2)
+	assert.Equal(t, Trade{OrderID: 1, LocationID: 60003760, RegionID: 10000002, TypeID: 34,
+		Price: 5, Quantity: 10, Side: "buy", Time: now}, trades[0])
+	assert.Equal(t, Trade{OrderID: 3, LocationID: 60003760, RegionID: 10000002, TypeID: 34,
+		Price: 3, Quantity: 5, Side: "sell", Filled: true, Time: now}, trades[1])
+}
+
+func TestCandles(t *testing.T) {
+	c := newCandleStore("")
+	start := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	closed := c.add([]Trade{
+		{LocationID: 60003760, RegionID: 10000002, TypeID: 34, Price: 5, Quantity: 10, Time: start.Add(time.Minute)},
+		{LocationID: 60003760, RegionID: 10000002, TypeID: 34, Price: 7, Quantity: 5, Time: start.Add(2 * time.Minute)},
+		{LocationID: 60003760, RegionID: 10000002, TypeID: 34, Price: 4, Quantity: 1, Time: start.Add(3 * time.Minute)},
+	})
+	assert.Len(t, closed, 0)
+
+	// The next 5 minute candle closes the first, for both location and region
+	closed = c.add([]Trade{
+		{LocationID: 60003760, RegionID: 10000002, TypeID: 34, Price: 6, Quantity: 1, Time: start.Add(6 * time.Minute)},
+	})
+	assert.Len(t, closed, 2)
+	for _, candle := range closed {
+		assert.Equal(t, "5m", candle.Interval)
+		assert.Equal(t, start, candle.Start)
+		assert.Equal(t, 5.0, candle.Open)
+		assert.Equal(t, 7.0, candle.High)
+		assert.Equal(t, 4.0, candle.Low)
+		assert.Equal(t, 4.0, candle.Close)
+		assert.Equal(t, int64(16), candle.Volume)
+		assert.Equal(t, 3, candle.Trades)
+		assert.True(t, candle.Closed)
+	}
+
+	candles := c.find("5m", func(candle Candle) bool { return candle.LocationID == 60003760 }, 10)
+	assert.Len(t, candles, 2)
+
+	// Time passing closes the rest
+	closed = c.closeExpired(start.Add(25 * time.Hour))
+	assert.Len(t, closed, 6)
+
+	// A structure has one series whichever poll its trades came from
+	c.add([]Trade{
+		{LocationID: 1022734985679, TypeID: 34, Price: 5, Quantity: 1, Time: start.Add(time.Minute)},
+		{LocationID: 1022734985679, RegionID: 10000002, TypeID: 34, Price: 6, Quantity: 2, Time: start.Add(2 * time.Minute)},
+	})
+	candles = c.find("5m", func(candle Candle) bool { return candle.LocationID == 1022734985679 }, 10)
+	if assert.Len(t, candles, 1) {
+		assert.Equal(t, int64(10000002), candles[0].RegionID)
+		assert.Equal(t, int64(3), candles[0].Volume)
+	}
+}
+
+func TestCandleRetention(t *testing.T) {
+	dir := t.TempDir()
+	c := newCandleStore(dir)
+	start := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	trade := func(at time.Duration) Trade {
+		return Trade{LocationID: 60003760, TypeID: 34, Price: 5, Quantity: 1, Time: start.Add(at)}
+	}
+	c.add([]Trade{trade(0)})
+	c.add([]Trade{trade(10 * time.Minute)})
+	c.add([]Trade{trade(24*time.Hour + 10*time.Minute)})
+
+	lines := func() []string {
+		b, err := os.ReadFile(filepath.Join(dir, "candles-5m.ndjson"))
+		assert.Nil(t, err)
+		return strings.Split(strings.TrimSpace(string(b)), "\n")
+	}
+	assert.Len(t, lines(), 2)
+
+	// Only the last day of 5 minute candles is kept
+	c.prune(start.Add(24*time.Hour + 10*time.Minute))
+	if assert.Len(t, lines(), 1) {
+		candle := Candle{}
+		assert.Nil(t, json.Unmarshal([]byte(lines()[0]), &candle))
+		assert.Equal(t, start.Add(10*time.Minute), candle.Start)
+	}
+	files, _ := os.ReadDir(dir)
+	assert.Len(t, files, 3)
+}
+
+func TestCandleRestart(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+	trade := func(at time.Duration, price float64) Trade {
+		return Trade{LocationID: 60003760, RegionID: 10000002, TypeID: 34, Price: price, Quantity: 1, Time: start.Add(at)}
+	}
+	c := newCandleStore(dir)
+	c.add([]Trade{trade(0, 5), trade(time.Minute, 6)})
+	c.add([]Trade{trade(10*time.Minute, 7)})
+	closed := c.closeExpired(start.Add(time.Hour))
+	assert.NotEmpty(t, closed)
+	all := func(Candle) bool { return true }
+	want := c.find("5m", all, 10)
+
+	// The closed candles are back after a restart, for the region and the location
+	restarted := newCandleStore(dir)
+	restarted.load(start.Add(time.Hour))
+	assert.ElementsMatch(t, want, restarted.find("5m", all, 10))
+	assert.ElementsMatch(t, c.find("1h", all, 10), restarted.find("1h", all, 10))
+	assert.Len(t, restarted.find("5m", func(c Candle) bool { return c.LocationID == 0 }, 10), 2)
+
+	// Trades for candles that have closed are too late
+	assert.Empty(t, restarted.add([]Trade{trade(10*time.Minute, 8)}))
+	assert.ElementsMatch(t, want, restarted.find("5m", all, 10))
+
+	// Candles older than the window are left on disk
+	old := newCandleStore(dir)
+	old.load(start.Add(48 * time.Hour))
+	assert.Empty(t, old.find("5m", all, 10))
+	assert.Len(t, old.find("1h", all, 10), 2)
+}
+
+func TestRecordTradesOnce(t *testing.T) {
+	const (
+		region    = int64(10000002)
+		structure = int64(1022734985679)
+	)
+	mw, err := NewMarketWatch(DefaultConfig())
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	now := time.Now().UTC()
+	changes := []OrderChange{
+		{OrderID: 1, LocationId: 60003760, TypeID: 34, Price: 5, VolumeChange: 10, TimeChanged: now},
+		{OrderID: 2, LocationId: structure, TypeID: 34, Price: 6, VolumeChange: 5, TimeChanged: now},
+	}
+
+	// A structure seen by the region poll but not polled directly counts there
+	state := mw.createStructureState(structure)
+	mw.recordTrades(region, now, changes, nil)
+	assert.Len(t, mw.trades.find(func(Trade) bool { return true }, now, 10), 2)
+
+	// Once it is polled only its own poll counts it
+	assert.True(t, mw.claimStructure(state))
+	mw.recordTrades(region, now, changes, nil)
+	mw.recordTrades(0, now, changes[1:], nil)
+	trades := mw.trades.find(func(t Trade) bool { return t.LocationID == structure }, now, 10)
+	if assert.Len(t, trades, 2) {
+		// with the region the structure was seen in
+		assert.Equal(t, region, trades[0].RegionID)
+		assert.Equal(t, region, trades[1].RegionID)
+	}
+	assert.Len(t, mw.trades.find(func(t Trade) bool { return t.LocationID == 60003760 }, now, 10), 2)
+}
@@ -1,6 +1,7 @@
 package marketwatch
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 	"time"
@@ -18,12 +19,30 @@ func getPages(r *http.Response) (int32, error) {
 	return pages, err
 }
 
+// sleep for a duration, returning false if the context finished first
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// How long to wait after the cache expires, or to retry when it already has
+var (
+	cacheExpiryPadding = time.Second * 15
+	cacheExpiredRetry  = time.Second * 10
+)
+
 func timeUntilCacheExpires(r *http.Response) time.Duration {
 	duration := time.Until(goesi.CacheExpires(r))
 	if duration < time.Second {
-		duration = time.Second * 10
+		duration = cacheExpiredRetry
 	} else {
-		duration += time.Second * 15
+		duration += cacheExpiryPadding
 	}
 	return duration
 }
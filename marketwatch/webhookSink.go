@@ -0,0 +1,425 @@
+package marketwatch
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/antihax/eve-marketwatch/wsbroadcast"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WebhookConfig posts batches of events to an HTTP endpoint
+type WebhookConfig struct {
+	URL string `yaml:"url"`
+
+	// Signs each request in the X-Marketwatch-Signature header, unsigned when empty
+	Secret string `yaml:"secret"`
+
+	// Only send these channels (market, contract, book, trades or candles) and
+	// actions, everything when empty
+	Channels []string `yaml:"channels"`
+	Actions  []string `yaml:"actions"`
+
+	// Only send what matches, as the websocket filter
+	Regions   []int64 `yaml:"regions"`
+	Locations []int64 `yaml:"locations"`
+	Types     []int64 `yaml:"types"`
+
+	// Send once this many events are waiting, or they have waited this long.
+	// Defaults to 100 and 5s.
+	BatchSize int           `yaml:"batch_size"`
+	BatchWait time.Duration `yaml:"batch_wait"`
+
+	// Most orders, contracts, trades or candles in a batch, larger events are
+	// split across batches. 1000 by default.
+	BatchItems int `yaml:"batch_items"`
+
+	// Attempts at a batch, waiting RetryWait doubling after each failure.
+	// Defaults to 5 and 1s.
+	MaxAttempts int           `yaml:"max_attempts"`
+	RetryWait   time.Duration `yaml:"retry_wait"`
+
+	// Time allowed for each request, 10s by default
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Time allowed for sending what is waiting at shutdown, after which the
+	// rest goes to the dead letter file. 10s by default.
+	CloseTimeout time.Duration `yaml:"close_timeout"`
+
+	// Batches waiting to be sent before any more go to the dead letter file, 100 by default
+	Queue int `yaml:"queue"`
+
+	// File that batches which could not be delivered are appended to, logged only when empty
+	DeadLetter string `yaml:"dead_letter"`
+}
+
+// Validate checks the settings are usable
+func (c *WebhookConfig) Validate() error {
+	u, err := url.Parse(c.URL)
+	switch {
+	case err != nil:
+		return fmt.Errorf("webhook url: %v", err)
+	case u.Scheme != "http" && u.Scheme != "https":
+		return fmt.Errorf("webhook url %q must be http or https", c.URL)
+	case c.BatchSize < 0 || c.BatchWait < 0 || c.BatchItems < 0 || c.MaxAttempts < 0 || c.RetryWait < 0 || c.Timeout < 0 || c.CloseTimeout < 0 || c.Queue < 0:
+		return fmt.Errorf("webhook %s settings cannot be negative", u.Host)
+	}
+	for _, ch := range c.Channels {
+		if _, ok := eventActions[ch]; !ok {
+			return fmt.Errorf("webhook %s: unknown channel %q", u.Host, ch)
+		}
+	}
+	for _, a := range c.Actions {
+		if !knownAction(a) {
+			return fmt.Errorf("webhook %s: unknown action %q", u.Host, a)
+		}
+	}
+	return nil
+}
+
+// knownAction checks an action is sent on any channel
+func knownAction(action string) bool {
+	for _, actions := range eventActions {
+		for _, a := range actions {
+			if a == action {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withDefaults fills in anything left unset
+func (c WebhookConfig) withDefaults() WebhookConfig {
+	if c.BatchSize == 0 {
+		c.BatchSize = 100
+	}
+	if c.BatchWait == 0 {
+		c.BatchWait = 5 * time.Second
+	}
+	if c.BatchItems == 0 {
+		c.BatchItems = 1000
+	}
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = 5
+	}
+	if c.RetryWait == 0 {
+		c.RetryWait = time.Second
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.CloseTimeout == 0 {
+		c.CloseTimeout = 10 * time.Second
+	}
+	if c.Queue == 0 {
+		c.Queue = 100
+	}
+	return c
+}
+
+// WebhookSink posts batches of events to an HTTP endpoint as a JSON array of
+// messages in the websocket format. Delivery happens in the background so a
+// slow endpoint never holds up polling. Batches are retried with exponential
+// backoff, and appended to the dead letter file if they still fail, fail with
+// a client error or too many are waiting.
+//
+// Each request carries a unique X-Marketwatch-Delivery ID, the same on every
+// attempt, and when a secret is configured an X-Marketwatch-Signature header
+// of "sha256=" and the hex HMAC-SHA256 of the body.
+type WebhookSink struct {
+	config WebhookConfig
+	filter *wsbroadcast.Filter
+	client *http.Client
+
+	// events waiting to be batched, and the items in them
+	mutex   sync.Mutex
+	pending []Message
+	items   int
+
+	// batches waiting to be sent, and the background goroutines
+	batches   chan []Message
+	stop      chan struct{}
+	batching  chan struct{}
+	delivered chan struct{}
+
+	// cancelled when Close runs out of time
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// dead letter file writes
+	deadMutex sync.Mutex
+}
+
+// NewWebhookSink starts delivering to an endpoint. Close it to send what is left.
+func NewWebhookSink(config WebhookConfig) *WebhookSink {
+	config = config.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &WebhookSink{
+		config:    config,
+		filter:    wsbroadcast.NewFilter(config.Regions, config.Locations, config.Types),
+		client:    &http.Client{Timeout: config.Timeout},
+		batches:   make(chan []Message, config.Queue),
+		stop:      make(chan struct{}),
+		batching:  make(chan struct{}),
+		delivered: make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	go w.runBatches()
+	go w.runDelivery()
+	return w
+}
+
+// Write adds an event to the next batch if it matches the endpoint
+func (w *WebhookSink) Write(e Event) error {
+	if !contains(w.config.Channels, e.channel()) || !contains(w.config.Actions, e.Action()) {
+		return nil
+	}
+	e, ok := filterEvent(e, w.filter)
+	if !ok {
+		return nil
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, m := range eventMessage(e).chunks(w.config.BatchItems) {
+		// Keep batches within BatchItems, starting another if this would not fit
+		if w.items+m.Len() > w.config.BatchItems {
+			w.flush()
+		}
+		w.pending = append(w.pending, m)
+		w.items += m.Len()
+		if len(w.pending) >= w.config.BatchSize || w.items >= w.config.BatchItems {
+			w.flush()
+		}
+	}
+	return nil
+}
+
+// Close sends what is waiting, for up to CloseTimeout. Batches that fail are
+// not retried, and those still waiting when the time is up go straight to the
+// dead letter file.
+func (w *WebhookSink) Close() error {
+	defer w.cancel()
+	close(w.stop)
+	<-w.batching
+	w.mutex.Lock()
+	w.flush()
+	w.mutex.Unlock()
+	close(w.batches)
+
+	timer := time.NewTimer(w.config.CloseTimeout)
+	defer timer.Stop()
+	select {
+	case <-w.delivered:
+	case <-timer.C:
+		w.cancel()
+		<-w.delivered
+	}
+	return nil
+}
+
+// flush queues the pending events as a batch. Must hold the mutex.
+func (w *WebhookSink) flush() {
+	if len(w.pending) == 0 {
+		return
+	}
+	batch := w.pending
+	w.pending, w.items = nil, 0
+	select {
+	case w.batches <- batch:
+	default:
+		w.deadLetter(batch, "", errors.New("too many batches waiting"))
+	}
+}
+
+// runBatches sends whatever is waiting every BatchWait
+func (w *WebhookSink) runBatches() {
+	defer close(w.batching)
+	ticker := time.NewTicker(w.config.BatchWait)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mutex.Lock()
+			w.flush()
+			w.mutex.Unlock()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// runDelivery posts each batch in turn
+func (w *WebhookSink) runDelivery() {
+	defer close(w.delivered)
+	for batch := range w.batches {
+		w.deliver(batch)
+	}
+}
+
+// deliver a batch, retrying with backoff until it succeeds or gives up
+func (w *WebhookSink) deliver(batch []Message) {
+	if err := w.ctx.Err(); err != nil {
+		w.deadLetter(batch, "", errors.New("out of time at shutdown"))
+		return
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		w.deadLetter(batch, "", err)
+		return
+	}
+	id := deliveryID()
+
+	wait := w.config.RetryWait
+	for attempt := 1; ; attempt++ {
+		retry, err := w.post(id, body)
+		if err == nil {
+			metricWebhookDeliveries.WithLabelValues("ok").Inc()
+			return
+		}
+
+		// Give up on client errors, the last attempt and at shutdown
+		stopping := false
+		select {
+		case <-w.stop:
+			stopping = true
+		default:
+		}
+		if !retry || attempt >= w.config.MaxAttempts || stopping {
+			metricWebhookDeliveries.WithLabelValues("failed").Inc()
+			w.deadLetter(batch, id, err)
+			return
+		}
+
+		metricWebhookDeliveries.WithLabelValues("retry").Inc()
+		select {
+		case <-time.After(wait):
+		case <-w.stop:
+		}
+		wait *= 2
+	}
+}
+
+// post sends the body once, returning whether a failure is worth retrying
+func (w *WebhookSink) post(id string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "eve-marketwatch")
+	req.Header.Set("X-Marketwatch-Delivery", id)
+	if w.config.Secret != "" {
+		req.Header.Set("X-Marketwatch-Signature", Signature(w.config.Secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	// Drain so the connection is reused
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook %s: %s", w.config.URL, resp.Status)
+	default:
+		return false, fmt.Errorf("webhook %s: %s", w.config.URL, resp.Status)
+	}
+}
+
+// deadLetter appends a batch that could not be delivered to the dead letter
+// file, one line of JSON each with why it failed
+func (w *WebhookSink) deadLetter(batch []Message, id string, reason error) {
+	log.Printf("webhook %s: dropping %d events: %v\n", w.config.URL, len(batch), reason)
+	if w.config.DeadLetter == "" {
+		return
+	}
+
+	b, err := json.Marshal(struct {
+		URL      string    `json:"url"`
+		Delivery string    `json:"delivery,omitempty"`
+		Error    string    `json:"error"`
+		Time     time.Time `json:"time"`
+		Events   []Message `json:"events"`
+	}{w.config.URL, id, reason.Error(), time.Now().UTC(), batch})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	w.deadMutex.Lock()
+	defer w.deadMutex.Unlock()
+	f, err := os.OpenFile(w.config.DeadLetter, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("failed to write dead letter: %v\n", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		log.Printf("failed to write dead letter: %v\n", err)
+	}
+}
+
+// Signature is the X-Marketwatch-Signature header for a body, for receivers
+// to compare against with hmac.Equal
+func Signature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliveryID is a random ID for each batch so receivers can drop repeats
+func deliveryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// contains checks for a value in a list, an empty list contains everything
+func contains(list []string, v string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, l := range list {
+		if l == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Metrics
+var (
+	metricWebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "evemarketwatch",
+		Subsystem: "webhook",
+		Name:      "deliveries",
+		Help:      "Webhook batch attempts by result: ok, retry or failed.",
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		metricWebhookDeliveries,
+	)
+}
@@ -0,0 +1,248 @@
+package marketwatch
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antihax/goesi/esi"
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookReceiver records the batches posted to it, replying with the statuses given in turn
+type webhookReceiver struct {
+	mutex      sync.Mutex
+	statuses   []int
+	batches    [][]frame
+	deliveries []string
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.deliveries = append(r.deliveries, req.Header.Get("X-Marketwatch-Delivery"))
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+
+	// Only signed requests are accepted
+	if !hmac.Equal([]byte(req.Header.Get("X-Marketwatch-Signature")), []byte(Signature("secret", body))) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	batch := []frame{}
+	json.Unmarshal(body, &batch)
+	r.batches = append(r.batches, batch)
+}
+
+func (r *webhookReceiver) received() ([][]frame, []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.batches, r.deliveries
+}
+
+func TestWebhookSink(t *testing.T) {
+	polled := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	order := func(orderID int64, typeID int32) OrderAdded {
+		o := testOrder(orderID, 100, 5)
+		o.TypeId = typeID
+		return OrderAdded{RegionID: 10000002, Orders: []esi.GetMarketsRegionIdOrders200Ok{o}, PolledAt: polled}
+	}
+	contract := ContractAdded{RegionID: 10000002, PolledAt: polled}
+
+	t.Run("batches", func(t *testing.T) {
+		receiver := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable}}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		sink := NewWebhookSink(WebhookConfig{
+			URL:       server.URL,
+			Secret:    "secret",
+			Channels:  []string{"market"},
+			Types:     []int64{34},
+			BatchSize: 2,
+			BatchWait: time.Hour,
+			RetryWait: time.Millisecond,
+		})
+		assert.Nil(t, sink.Write(order(1, 34)))
+		assert.Nil(t, sink.Write(contract))
+		assert.Nil(t, sink.Write(order(2, 35)))
+		assert.Nil(t, sink.Write(order(3, 34)))
+		assert.Nil(t, sink.Write(order(4, 34)))
+		assert.Eventually(t, func() bool {
+			batches, _ := receiver.received()
+			return len(batches) == 1
+		}, 5*time.Second, time.Millisecond)
+		assert.Nil(t, sink.Close())
+
+		// The first batch is retried with the same delivery ID, the rest is sent on close
+		batches, deliveries := receiver.received()
+		if assert.Len(t, batches, 2) {
+			assert.Len(t, batches[0], 2)
+			assert.Equal(t, "addition", batches[0][0].Action)
+			assert.Equal(t, SchemaVersion, batches[0][0].SchemaVersion)
+			assert.Len(t, batches[1], 1)
+		}
+		if assert.Len(t, deliveries, 3) {
+			assert.Equal(t, deliveries[0], deliveries[1])
+			assert.NotEqual(t, deliveries[1], deliveries[2])
+		}
+	})
+
+	t.Run("dead letter", func(t *testing.T) {
+		receiver := &webhookReceiver{statuses: []int{http.StatusBadRequest, 500, 500, 500}}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		deadLetter := filepath.Join(t.TempDir(), "dead.ndjson")
+		sink := NewWebhookSink(WebhookConfig{
+			URL:         server.URL,
+			Secret:      "secret",
+			BatchSize:   1,
+			MaxAttempts: 3,
+			RetryWait:   time.Millisecond,
+			DeadLetter:  deadLetter,
+		})
+		// Client errors are not retried, server errors until the attempts run out
+		assert.Nil(t, sink.Write(order(1, 34)))
+		assert.Nil(t, sink.Write(order(2, 34)))
+		assert.Eventually(t, func() bool {
+			_, deliveries := receiver.received()
+			return len(deliveries) == 4
+		}, 5*time.Second, time.Millisecond)
+		assert.Nil(t, sink.Close())
+
+		batches, deliveries := receiver.received()
+		assert.Empty(t, batches)
+		assert.Len(t, deliveries, 4)
+
+		f, err := os.Open(deadLetter)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer f.Close()
+		type deadLetterLine struct {
+			URL      string  `json:"url"`
+			Delivery string  `json:"delivery"`
+			Error    string  `json:"error"`
+			Events   []frame `json:"events"`
+		}
+		lines := []deadLetterLine{}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := deadLetterLine{}
+			assert.Nil(t, json.Unmarshal(scanner.Bytes(), &line))
+			lines = append(lines, line)
+		}
+		if assert.Len(t, lines, 2) {
+			assert.Equal(t, server.URL, lines[0].URL)
+			assert.Equal(t, deliveries[0], lines[0].Delivery)
+			assert.Contains(t, lines[0].Error, "400")
+			assert.Len(t, lines[0].Events, 1)
+			assert.Contains(t, lines[1].Error, "500")
+		}
+	})
+	t.Run("batch items", func(t *testing.T) {
+		receiver := &webhookReceiver{}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		sink := NewWebhookSink(WebhookConfig{
+			URL:        server.URL,
+			Secret:     "secret",
+			BatchWait:  time.Hour,
+			BatchItems: 3,
+		})
+		orders := func(ids ...int64) OrderAdded {
+			e := OrderAdded{RegionID: 10000002, PolledAt: polled}
+			for _, id := range ids {
+				e.Orders = append(e.Orders, testOrder(id, 100, 5))
+			}
+			return e
+		}
+
+		// Large events are split, and small ones fill the space left
+		assert.Nil(t, sink.Write(orders(1, 2, 3, 4, 5)))
+		assert.Nil(t, sink.Write(orders(6)))
+		assert.Nil(t, sink.Write(orders(7, 8)))
+		assert.Nil(t, sink.Close())
+
+		batches, _ := receiver.received()
+		if assert.Len(t, batches, 3) {
+			items := func(batch []frame) []int {
+				n := []int{}
+				for _, f := range batch {
+					orders := []esi.GetMarketsRegionIdOrders200Ok{}
+					assert.Nil(t, json.Unmarshal(f.Payload, &orders))
+					n = append(n, len(orders))
+				}
+				return n
+			}
+			assert.Equal(t, []int{3}, items(batches[0]))
+			assert.Equal(t, []int{2, 1}, items(batches[1]))
+			assert.Equal(t, []int{2}, items(batches[2]))
+		}
+	})
+
+	t.Run("close timeout", func(t *testing.T) {
+		// The endpoint hangs until the test ends
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		deadLetter := filepath.Join(t.TempDir(), "dead.ndjson")
+		sink := NewWebhookSink(WebhookConfig{
+			URL:          server.URL,
+			BatchSize:    1,
+			CloseTimeout: 50 * time.Millisecond,
+			DeadLetter:   deadLetter,
+		})
+		for i := int64(1); i <= 5; i++ {
+			assert.Nil(t, sink.Write(order(i, 34)))
+		}
+
+		// Everything left goes to the dead letter file once the time is up
+		start := time.Now()
+		assert.Nil(t, sink.Close())
+		assert.Less(t, time.Since(start), 5*time.Second)
+		b, err := os.ReadFile(deadLetter)
+		assert.Nil(t, err)
+		assert.Equal(t, 5, strings.Count(string(b), "\n"))
+	})
+}
+
+func TestWebhookConfig(t *testing.T) {
+	c := WebhookConfig{URL: "https://example.com/hook", Channels: []string{"contract", "candles"}, Actions: []string{"contractAddition", "candle"}}
+	assert.Nil(t, c.Validate())
+
+	c.Channels = []string{"contracts"}
+	assert.NotNil(t, c.Validate())
+	c.Channels = nil
+	c.Actions = []string{"contractAdded"}
+	assert.NotNil(t, c.Validate())
+	c.Actions = nil
+	c.CloseTimeout = -time.Second
+	assert.NotNil(t, c.Validate())
+	c.CloseTimeout = 0
+	c.URL = "ftp://example.com"
+	assert.NotNil(t, c.Validate())
+}
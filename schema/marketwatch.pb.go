@@ -0,0 +1,2535 @@
+// Protobuf encoding of the websocket messages, selected with ?encoding=protobuf
+// or the "protobuf" websocket subprotocol. Each binary frame is one Frame.
+// The gRPC service streams the same frames.
+//
+// Field numbers are never reused. schema_version follows the JSON Schema in
+// marketwatch.schema.json and is bumped on any breaking change.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: marketwatch.proto
+
+package schema
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StreamRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Everything when unset
+	Filter *Filter `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// Only stream what changes, without the current state first
+	SkipSnapshot  bool `protobuf:"varint,2,opt,name=skip_snapshot,json=skipSnapshot,proto3" json:"skip_snapshot,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamRequest) Reset() {
+	*x = StreamRequest{}
+	mi := &file_marketwatch_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamRequest) ProtoMessage() {}
+
+func (x *StreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamRequest.ProtoReflect.Descriptor instead.
+func (*StreamRequest) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StreamRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *StreamRequest) GetSkipSnapshot() bool {
+	if x != nil {
+		return x.SkipSnapshot
+	}
+	return false
+}
+
+type GetOrderBookRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	LocationId int64                  `protobuf:"varint,1,opt,name=location_id,json=locationId,proto3" json:"location_id,omitempty"`
+	TypeId     int32                  `protobuf:"varint,2,opt,name=type_id,json=typeId,proto3" json:"type_id,omitempty"`
+	// Price levels per side, at most 100. 5 when unset, and only the top of book when 0.
+	Depth         *int32 `protobuf:"varint,3,opt,name=depth,proto3,oneof" json:"depth,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOrderBookRequest) Reset() {
+	*x = GetOrderBookRequest{}
+	mi := &file_marketwatch_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOrderBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrderBookRequest) ProtoMessage() {}
+
+func (x *GetOrderBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOrderBookRequest.ProtoReflect.Descriptor instead.
+func (*GetOrderBookRequest) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetOrderBookRequest) GetLocationId() int64 {
+	if x != nil {
+		return x.LocationId
+	}
+	return 0
+}
+
+func (x *GetOrderBookRequest) GetTypeId() int32 {
+	if x != nil {
+		return x.TypeId
+	}
+	return 0
+}
+
+func (x *GetOrderBookRequest) GetDepth() int32 {
+	if x != nil && x.Depth != nil {
+		return *x.Depth
+	}
+	return 0
+}
+
+// OrderBook is the top of book with a price ladder for each side
+type OrderBook struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Top           *BookTop               `protobuf:"bytes,1,opt,name=top,proto3" json:"top,omitempty"`
+	Buy           []*PriceLevel          `protobuf:"bytes,2,rep,name=buy,proto3" json:"buy,omitempty"`
+	Sell          []*PriceLevel          `protobuf:"bytes,3,rep,name=sell,proto3" json:"sell,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderBook) Reset() {
+	*x = OrderBook{}
+	mi := &file_marketwatch_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderBook) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderBook) ProtoMessage() {}
+
+func (x *OrderBook) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderBook.ProtoReflect.Descriptor instead.
+func (*OrderBook) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OrderBook) GetTop() *BookTop {
+	if x != nil {
+		return x.Top
+	}
+	return nil
+}
+
+func (x *OrderBook) GetBuy() []*PriceLevel {
+	if x != nil {
+		return x.Buy
+	}
+	return nil
+}
+
+func (x *OrderBook) GetSell() []*PriceLevel {
+	if x != nil {
+		return x.Sell
+	}
+	return nil
+}
+
+// PriceLevel is the total volume at one price
+type PriceLevel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Price         float64                `protobuf:"fixed64,1,opt,name=price,proto3" json:"price,omitempty"`
+	Volume        int64                  `protobuf:"varint,2,opt,name=volume,proto3" json:"volume,omitempty"`
+	Orders        int32                  `protobuf:"varint,3,opt,name=orders,proto3" json:"orders,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PriceLevel) Reset() {
+	*x = PriceLevel{}
+	mi := &file_marketwatch_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PriceLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriceLevel) ProtoMessage() {}
+
+func (x *PriceLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriceLevel.ProtoReflect.Descriptor instead.
+func (*PriceLevel) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PriceLevel) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *PriceLevel) GetVolume() int64 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+func (x *PriceLevel) GetOrders() int32 {
+	if x != nil {
+		return x.Orders
+	}
+	return 0
+}
+
+type GetContractRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContractId    int32                  `protobuf:"varint,1,opt,name=contract_id,json=contractId,proto3" json:"contract_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetContractRequest) Reset() {
+	*x = GetContractRequest{}
+	mi := &file_marketwatch_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetContractRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetContractRequest) ProtoMessage() {}
+
+func (x *GetContractRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetContractRequest.ProtoReflect.Descriptor instead.
+func (*GetContractRequest) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetContractRequest) GetContractId() int32 {
+	if x != nil {
+		return x.ContractId
+	}
+	return 0
+}
+
+// Frame wraps every message. The action decides which payload is set.
+type Frame struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Zero on replies to commands and snapshot markers
+	SchemaVersion uint32 `protobuf:"varint,1,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	Action        string `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	// Position in the stream, zero in the dump sent on connect
+	Seq uint64 `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`
+	// Region the payload came from, zero for structure markets
+	RegionId int64 `protobuf:"varint,4,opt,name=region_id,json=regionId,proto3" json:"region_id,omitempty"`
+	// When the poll that found the payload started
+	Time *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
+	// The stream seq belongs to, which changes when the server restarts
+	Stream string `protobuf:"bytes,6,opt,name=stream,proto3" json:"stream,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*Frame_Orders
+	//	*Frame_OrderChanges
+	//	*Frame_Contracts
+	//	*Frame_ContractChanges
+	//	*Frame_BookTops
+	//	*Frame_Trades
+	//	*Frame_Candles
+	//	*Frame_Ack
+	//	*Frame_Snapshot
+	Payload       isFrame_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Frame) Reset() {
+	*x = Frame{}
+	mi := &file_marketwatch_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Frame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Frame) ProtoMessage() {}
+
+func (x *Frame) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Frame.ProtoReflect.Descriptor instead.
+func (*Frame) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Frame) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *Frame) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *Frame) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Frame) GetRegionId() int64 {
+	if x != nil {
+		return x.RegionId
+	}
+	return 0
+}
+
+func (x *Frame) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Frame) GetStream() string {
+	if x != nil {
+		return x.Stream
+	}
+	return ""
+}
+
+func (x *Frame) GetPayload() isFrame_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *Frame) GetOrders() *Orders {
+	if x != nil {
+		if x, ok := x.Payload.(*Frame_Orders); ok {
+			return x.Orders
+		}
+	}
+	return nil
+}
+
+func (x *Frame) GetOrderChanges() *OrderChanges {
+	if x != nil {
+		if x, ok := x.Payload.(*Frame_OrderChanges); ok {
+			return x.OrderChanges
+		}
+	}
+	return nil
+}
+
+func (x *Frame) GetContracts() *Contracts {
+	if x != nil {
+		if x, ok := x.Payload.(*Frame_Contracts); ok {
+			return x.Contracts
+		}
+	}
+	return nil
+}
+
+func (x *Frame) GetContractChanges() *ContractChanges {
+	if x != nil {
+		if x, ok := x.Payload.(*Frame_ContractChanges); ok {
+			return x.ContractChanges
+		}
+	}
+	return nil
+}
+
+func (x *Frame) GetBookTops() *BookTops {
+	if x != nil {
+		if x, ok := x.Payload.(*Frame_BookTops); ok {
+			return x.BookTops
+		}
+	}
+	return nil
+}
+
+func (x *Frame) GetTrades() *Trades {
+	if x != nil {
+		if x, ok := x.Payload.(*Frame_Trades); ok {
+			return x.Trades
+		}
+	}
+	return nil
+}
+
+func (x *Frame) GetCandles() *Candles {
+	if x != nil {
+		if x, ok := x.Payload.(*Frame_Candles); ok {
+			return x.Candles
+		}
+	}
+	return nil
+}
+
+func (x *Frame) GetAck() *Ack {
+	if x != nil {
+		if x, ok := x.Payload.(*Frame_Ack); ok {
+			return x.Ack
+		}
+	}
+	return nil
+}
+
+func (x *Frame) GetSnapshot() *Snapshot {
+	if x != nil {
+		if x, ok := x.Payload.(*Frame_Snapshot); ok {
+			return x.Snapshot
+		}
+	}
+	return nil
+}
+
+type isFrame_Payload interface {
+	isFrame_Payload()
+}
+
+type Frame_Orders struct {
+	// addition
+	Orders *Orders `protobuf:"bytes,10,opt,name=orders,proto3,oneof"`
+}
+
+type Frame_OrderChanges struct {
+	// change and deletion
+	OrderChanges *OrderChanges `protobuf:"bytes,11,opt,name=order_changes,json=orderChanges,proto3,oneof"`
+}
+
+type Frame_Contracts struct {
+	// contractAddition
+	Contracts *Contracts `protobuf:"bytes,12,opt,name=contracts,proto3,oneof"`
+}
+
+type Frame_ContractChanges struct {
+	// contractChange and contractDeletion
+	ContractChanges *ContractChanges `protobuf:"bytes,13,opt,name=contract_changes,json=contractChanges,proto3,oneof"`
+}
+
+type Frame_BookTops struct {
+	// bookChange
+	BookTops *BookTops `protobuf:"bytes,14,opt,name=book_tops,json=bookTops,proto3,oneof"`
+}
+
+type Frame_Trades struct {
+	// trade
+	Trades *Trades `protobuf:"bytes,15,opt,name=trades,proto3,oneof"`
+}
+
+type Frame_Candles struct {
+	// candle
+	Candles *Candles `protobuf:"bytes,16,opt,name=candles,proto3,oneof"`
+}
+
+type Frame_Ack struct {
+	// ack, pong and error
+	Ack *Ack `protobuf:"bytes,17,opt,name=ack,proto3,oneof"`
+}
+
+type Frame_Snapshot struct {
+	// snapshotBegin and snapshotEnd
+	Snapshot *Snapshot `protobuf:"bytes,18,opt,name=snapshot,proto3,oneof"`
+}
+
+func (*Frame_Orders) isFrame_Payload() {}
+
+func (*Frame_OrderChanges) isFrame_Payload() {}
+
+func (*Frame_Contracts) isFrame_Payload() {}
+
+func (*Frame_ContractChanges) isFrame_Payload() {}
+
+func (*Frame_BookTops) isFrame_Payload() {}
+
+func (*Frame_Trades) isFrame_Payload() {}
+
+func (*Frame_Candles) isFrame_Payload() {}
+
+func (*Frame_Ack) isFrame_Payload() {}
+
+func (*Frame_Snapshot) isFrame_Payload() {}
+
+type Orders struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*Order               `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Orders) Reset() {
+	*x = Orders{}
+	mi := &file_marketwatch_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Orders) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Orders) ProtoMessage() {}
+
+func (x *Orders) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Orders.ProtoReflect.Descriptor instead.
+func (*Orders) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Orders) GetItems() []*Order {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// Order is an ESI market order
+type Order struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       int64                  `protobuf:"varint,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	LocationId    int64                  `protobuf:"varint,2,opt,name=location_id,json=locationId,proto3" json:"location_id,omitempty"`
+	SystemId      int32                  `protobuf:"varint,3,opt,name=system_id,json=systemId,proto3" json:"system_id,omitempty"`
+	TypeId        int32                  `protobuf:"varint,4,opt,name=type_id,json=typeId,proto3" json:"type_id,omitempty"`
+	IsBuyOrder    bool                   `protobuf:"varint,5,opt,name=is_buy_order,json=isBuyOrder,proto3" json:"is_buy_order,omitempty"`
+	Price         float64                `protobuf:"fixed64,6,opt,name=price,proto3" json:"price,omitempty"`
+	VolumeRemain  int32                  `protobuf:"varint,7,opt,name=volume_remain,json=volumeRemain,proto3" json:"volume_remain,omitempty"`
+	VolumeTotal   int32                  `protobuf:"varint,8,opt,name=volume_total,json=volumeTotal,proto3" json:"volume_total,omitempty"`
+	MinVolume     int32                  `protobuf:"varint,9,opt,name=min_volume,json=minVolume,proto3" json:"min_volume,omitempty"`
+	Range         string                 `protobuf:"bytes,10,opt,name=range,proto3" json:"range,omitempty"`
+	Duration      int32                  `protobuf:"varint,11,opt,name=duration,proto3" json:"duration,omitempty"`
+	Issued        *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=issued,proto3" json:"issued,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Order) Reset() {
+	*x = Order{}
+	mi := &file_marketwatch_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Order) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Order) ProtoMessage() {}
+
+func (x *Order) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Order.ProtoReflect.Descriptor instead.
+func (*Order) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Order) GetOrderId() int64 {
+	if x != nil {
+		return x.OrderId
+	}
+	return 0
+}
+
+func (x *Order) GetLocationId() int64 {
+	if x != nil {
+		return x.LocationId
+	}
+	return 0
+}
+
+func (x *Order) GetSystemId() int32 {
+	if x != nil {
+		return x.SystemId
+	}
+	return 0
+}
+
+func (x *Order) GetTypeId() int32 {
+	if x != nil {
+		return x.TypeId
+	}
+	return 0
+}
+
+func (x *Order) GetIsBuyOrder() bool {
+	if x != nil {
+		return x.IsBuyOrder
+	}
+	return false
+}
+
+func (x *Order) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Order) GetVolumeRemain() int32 {
+	if x != nil {
+		return x.VolumeRemain
+	}
+	return 0
+}
+
+func (x *Order) GetVolumeTotal() int32 {
+	if x != nil {
+		return x.VolumeTotal
+	}
+	return 0
+}
+
+func (x *Order) GetMinVolume() int32 {
+	if x != nil {
+		return x.MinVolume
+	}
+	return 0
+}
+
+func (x *Order) GetRange() string {
+	if x != nil {
+		return x.Range
+	}
+	return ""
+}
+
+func (x *Order) GetDuration() int32 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *Order) GetIssued() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Issued
+	}
+	return nil
+}
+
+type OrderChanges struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*OrderChange         `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderChanges) Reset() {
+	*x = OrderChanges{}
+	mi := &file_marketwatch_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderChanges) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderChanges) ProtoMessage() {}
+
+func (x *OrderChanges) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderChanges.ProtoReflect.Descriptor instead.
+func (*OrderChanges) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *OrderChanges) GetItems() []*OrderChange {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// OrderChange is a changed order. Deletions have no volume_remain and
+// volume_change is what was left.
+type OrderChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       int64                  `protobuf:"varint,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	LocationId    int64                  `protobuf:"varint,2,opt,name=location_id,json=locationId,proto3" json:"location_id,omitempty"`
+	TypeId        int32                  `protobuf:"varint,3,opt,name=type_id,json=typeId,proto3" json:"type_id,omitempty"`
+	VolumeChange  int32                  `protobuf:"varint,4,opt,name=volume_change,json=volumeChange,proto3" json:"volume_change,omitempty"`
+	VolumeRemain  int32                  `protobuf:"varint,5,opt,name=volume_remain,json=volumeRemain,proto3" json:"volume_remain,omitempty"`
+	Price         float64                `protobuf:"fixed64,6,opt,name=price,proto3" json:"price,omitempty"`
+	Duration      int32                  `protobuf:"varint,7,opt,name=duration,proto3" json:"duration,omitempty"`
+	IsBuyOrder    bool                   `protobuf:"varint,8,opt,name=is_buy_order,json=isBuyOrder,proto3" json:"is_buy_order,omitempty"`
+	Issued        *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=issued,proto3" json:"issued,omitempty"`
+	TimeChanged   *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=time_changed,json=timeChanged,proto3" json:"time_changed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderChange) Reset() {
+	*x = OrderChange{}
+	mi := &file_marketwatch_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderChange) ProtoMessage() {}
+
+func (x *OrderChange) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderChange.ProtoReflect.Descriptor instead.
+func (*OrderChange) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *OrderChange) GetOrderId() int64 {
+	if x != nil {
+		return x.OrderId
+	}
+	return 0
+}
+
+func (x *OrderChange) GetLocationId() int64 {
+	if x != nil {
+		return x.LocationId
+	}
+	return 0
+}
+
+func (x *OrderChange) GetTypeId() int32 {
+	if x != nil {
+		return x.TypeId
+	}
+	return 0
+}
+
+func (x *OrderChange) GetVolumeChange() int32 {
+	if x != nil {
+		return x.VolumeChange
+	}
+	return 0
+}
+
+func (x *OrderChange) GetVolumeRemain() int32 {
+	if x != nil {
+		return x.VolumeRemain
+	}
+	return 0
+}
+
+func (x *OrderChange) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *OrderChange) GetDuration() int32 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *OrderChange) GetIsBuyOrder() bool {
+	if x != nil {
+		return x.IsBuyOrder
+	}
+	return false
+}
+
+func (x *OrderChange) GetIssued() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Issued
+	}
+	return nil
+}
+
+func (x *OrderChange) GetTimeChanged() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeChanged
+	}
+	return nil
+}
+
+type Contracts struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*FullContract        `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Contracts) Reset() {
+	*x = Contracts{}
+	mi := &file_marketwatch_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Contracts) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Contracts) ProtoMessage() {}
+
+func (x *Contracts) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Contracts.ProtoReflect.Descriptor instead.
+func (*Contracts) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Contracts) GetItems() []*FullContract {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// FullContract is a contract with its items and bids
+type FullContract struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Contract      *Contract              `protobuf:"bytes,1,opt,name=contract,proto3" json:"contract,omitempty"`
+	Items         []*ContractItem        `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	Bids          []*ContractBid         `protobuf:"bytes,3,rep,name=bids,proto3" json:"bids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FullContract) Reset() {
+	*x = FullContract{}
+	mi := &file_marketwatch_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FullContract) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FullContract) ProtoMessage() {}
+
+func (x *FullContract) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FullContract.ProtoReflect.Descriptor instead.
+func (*FullContract) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *FullContract) GetContract() *Contract {
+	if x != nil {
+		return x.Contract
+	}
+	return nil
+}
+
+func (x *FullContract) GetItems() []*ContractItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *FullContract) GetBids() []*ContractBid {
+	if x != nil {
+		return x.Bids
+	}
+	return nil
+}
+
+// Contract is an ESI public contract
+type Contract struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	ContractId          int32                  `protobuf:"varint,1,opt,name=contract_id,json=contractId,proto3" json:"contract_id,omitempty"`
+	Type                string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	IssuerId            int32                  `protobuf:"varint,3,opt,name=issuer_id,json=issuerId,proto3" json:"issuer_id,omitempty"`
+	IssuerCorporationId int32                  `protobuf:"varint,4,opt,name=issuer_corporation_id,json=issuerCorporationId,proto3" json:"issuer_corporation_id,omitempty"`
+	ForCorporation      bool                   `protobuf:"varint,5,opt,name=for_corporation,json=forCorporation,proto3" json:"for_corporation,omitempty"`
+	StartLocationId     int64                  `protobuf:"varint,6,opt,name=start_location_id,json=startLocationId,proto3" json:"start_location_id,omitempty"`
+	EndLocationId       int64                  `protobuf:"varint,7,opt,name=end_location_id,json=endLocationId,proto3" json:"end_location_id,omitempty"`
+	Title               string                 `protobuf:"bytes,8,opt,name=title,proto3" json:"title,omitempty"`
+	Price               float64                `protobuf:"fixed64,9,opt,name=price,proto3" json:"price,omitempty"`
+	Buyout              float64                `protobuf:"fixed64,10,opt,name=buyout,proto3" json:"buyout,omitempty"`
+	Reward              float64                `protobuf:"fixed64,11,opt,name=reward,proto3" json:"reward,omitempty"`
+	Collateral          float64                `protobuf:"fixed64,12,opt,name=collateral,proto3" json:"collateral,omitempty"`
+	Volume              float64                `protobuf:"fixed64,13,opt,name=volume,proto3" json:"volume,omitempty"`
+	DaysToComplete      int32                  `protobuf:"varint,14,opt,name=days_to_complete,json=daysToComplete,proto3" json:"days_to_complete,omitempty"`
+	DateIssued          *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=date_issued,json=dateIssued,proto3" json:"date_issued,omitempty"`
+	DateExpired         *timestamppb.Timestamp `protobuf:"bytes,16,opt,name=date_expired,json=dateExpired,proto3" json:"date_expired,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *Contract) Reset() {
+	*x = Contract{}
+	mi := &file_marketwatch_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Contract) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Contract) ProtoMessage() {}
+
+func (x *Contract) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Contract.ProtoReflect.Descriptor instead.
+func (*Contract) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Contract) GetContractId() int32 {
+	if x != nil {
+		return x.ContractId
+	}
+	return 0
+}
+
+func (x *Contract) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Contract) GetIssuerId() int32 {
+	if x != nil {
+		return x.IssuerId
+	}
+	return 0
+}
+
+func (x *Contract) GetIssuerCorporationId() int32 {
+	if x != nil {
+		return x.IssuerCorporationId
+	}
+	return 0
+}
+
+func (x *Contract) GetForCorporation() bool {
+	if x != nil {
+		return x.ForCorporation
+	}
+	return false
+}
+
+func (x *Contract) GetStartLocationId() int64 {
+	if x != nil {
+		return x.StartLocationId
+	}
+	return 0
+}
+
+func (x *Contract) GetEndLocationId() int64 {
+	if x != nil {
+		return x.EndLocationId
+	}
+	return 0
+}
+
+func (x *Contract) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Contract) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Contract) GetBuyout() float64 {
+	if x != nil {
+		return x.Buyout
+	}
+	return 0
+}
+
+func (x *Contract) GetReward() float64 {
+	if x != nil {
+		return x.Reward
+	}
+	return 0
+}
+
+func (x *Contract) GetCollateral() float64 {
+	if x != nil {
+		return x.Collateral
+	}
+	return 0
+}
+
+func (x *Contract) GetVolume() float64 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+func (x *Contract) GetDaysToComplete() int32 {
+	if x != nil {
+		return x.DaysToComplete
+	}
+	return 0
+}
+
+func (x *Contract) GetDateIssued() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DateIssued
+	}
+	return nil
+}
+
+func (x *Contract) GetDateExpired() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DateExpired
+	}
+	return nil
+}
+
+type ContractItem struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	RecordId           int64                  `protobuf:"varint,1,opt,name=record_id,json=recordId,proto3" json:"record_id,omitempty"`
+	ItemId             int64                  `protobuf:"varint,2,opt,name=item_id,json=itemId,proto3" json:"item_id,omitempty"`
+	TypeId             int32                  `protobuf:"varint,3,opt,name=type_id,json=typeId,proto3" json:"type_id,omitempty"`
+	Quantity           int32                  `protobuf:"varint,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	IsIncluded         bool                   `protobuf:"varint,5,opt,name=is_included,json=isIncluded,proto3" json:"is_included,omitempty"`
+	IsBlueprintCopy    bool                   `protobuf:"varint,6,opt,name=is_blueprint_copy,json=isBlueprintCopy,proto3" json:"is_blueprint_copy,omitempty"`
+	MaterialEfficiency int32                  `protobuf:"varint,7,opt,name=material_efficiency,json=materialEfficiency,proto3" json:"material_efficiency,omitempty"`
+	TimeEfficiency     int32                  `protobuf:"varint,8,opt,name=time_efficiency,json=timeEfficiency,proto3" json:"time_efficiency,omitempty"`
+	Runs               int32                  `protobuf:"varint,9,opt,name=runs,proto3" json:"runs,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ContractItem) Reset() {
+	*x = ContractItem{}
+	mi := &file_marketwatch_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContractItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContractItem) ProtoMessage() {}
+
+func (x *ContractItem) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContractItem.ProtoReflect.Descriptor instead.
+func (*ContractItem) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ContractItem) GetRecordId() int64 {
+	if x != nil {
+		return x.RecordId
+	}
+	return 0
+}
+
+func (x *ContractItem) GetItemId() int64 {
+	if x != nil {
+		return x.ItemId
+	}
+	return 0
+}
+
+func (x *ContractItem) GetTypeId() int32 {
+	if x != nil {
+		return x.TypeId
+	}
+	return 0
+}
+
+func (x *ContractItem) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *ContractItem) GetIsIncluded() bool {
+	if x != nil {
+		return x.IsIncluded
+	}
+	return false
+}
+
+func (x *ContractItem) GetIsBlueprintCopy() bool {
+	if x != nil {
+		return x.IsBlueprintCopy
+	}
+	return false
+}
+
+func (x *ContractItem) GetMaterialEfficiency() int32 {
+	if x != nil {
+		return x.MaterialEfficiency
+	}
+	return 0
+}
+
+func (x *ContractItem) GetTimeEfficiency() int32 {
+	if x != nil {
+		return x.TimeEfficiency
+	}
+	return 0
+}
+
+func (x *ContractItem) GetRuns() int32 {
+	if x != nil {
+		return x.Runs
+	}
+	return 0
+}
+
+type ContractBid struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BidId         int32                  `protobuf:"varint,1,opt,name=bid_id,json=bidId,proto3" json:"bid_id,omitempty"`
+	Amount        float32                `protobuf:"fixed32,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	DateBid       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=date_bid,json=dateBid,proto3" json:"date_bid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContractBid) Reset() {
+	*x = ContractBid{}
+	mi := &file_marketwatch_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContractBid) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContractBid) ProtoMessage() {}
+
+func (x *ContractBid) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContractBid.ProtoReflect.Descriptor instead.
+func (*ContractBid) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ContractBid) GetBidId() int32 {
+	if x != nil {
+		return x.BidId
+	}
+	return 0
+}
+
+func (x *ContractBid) GetAmount() float32 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *ContractBid) GetDateBid() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DateBid
+	}
+	return nil
+}
+
+type ContractChanges struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*ContractChange      `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContractChanges) Reset() {
+	*x = ContractChanges{}
+	mi := &file_marketwatch_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContractChanges) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContractChanges) ProtoMessage() {}
+
+func (x *ContractChanges) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContractChanges.ProtoReflect.Descriptor instead.
+func (*ContractChanges) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ContractChanges) GetItems() []*ContractChange {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// ContractChange is an auction that received bids, or a contract that is
+// gone. expired is set if it ran out rather than being accepted.
+type ContractChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContractId    int32                  `protobuf:"varint,1,opt,name=contract_id,json=contractId,proto3" json:"contract_id,omitempty"`
+	LocationId    int64                  `protobuf:"varint,2,opt,name=location_id,json=locationId,proto3" json:"location_id,omitempty"`
+	Expired       bool                   `protobuf:"varint,3,opt,name=expired,proto3" json:"expired,omitempty"`
+	DateExpired   *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=date_expired,json=dateExpired,proto3" json:"date_expired,omitempty"`
+	Bids          []*ContractBid         `protobuf:"bytes,5,rep,name=bids,proto3" json:"bids,omitempty"`
+	Price         float64                `protobuf:"fixed64,6,opt,name=price,proto3" json:"price,omitempty"`
+	Type          string                 `protobuf:"bytes,7,opt,name=type,proto3" json:"type,omitempty"`
+	TimeChanged   *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=time_changed,json=timeChanged,proto3" json:"time_changed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContractChange) Reset() {
+	*x = ContractChange{}
+	mi := &file_marketwatch_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContractChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContractChange) ProtoMessage() {}
+
+func (x *ContractChange) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContractChange.ProtoReflect.Descriptor instead.
+func (*ContractChange) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ContractChange) GetContractId() int32 {
+	if x != nil {
+		return x.ContractId
+	}
+	return 0
+}
+
+func (x *ContractChange) GetLocationId() int64 {
+	if x != nil {
+		return x.LocationId
+	}
+	return 0
+}
+
+func (x *ContractChange) GetExpired() bool {
+	if x != nil {
+		return x.Expired
+	}
+	return false
+}
+
+func (x *ContractChange) GetDateExpired() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DateExpired
+	}
+	return nil
+}
+
+func (x *ContractChange) GetBids() []*ContractBid {
+	if x != nil {
+		return x.Bids
+	}
+	return nil
+}
+
+func (x *ContractChange) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *ContractChange) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ContractChange) GetTimeChanged() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeChanged
+	}
+	return nil
+}
+
+type BookTops struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*BookTop             `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BookTops) Reset() {
+	*x = BookTops{}
+	mi := &file_marketwatch_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookTops) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookTops) ProtoMessage() {}
+
+func (x *BookTops) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookTops.ProtoReflect.Descriptor instead.
+func (*BookTops) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *BookTops) GetItems() []*BookTop {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type BookTop struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LocationId    int64                  `protobuf:"varint,1,opt,name=location_id,json=locationId,proto3" json:"location_id,omitempty"`
+	TypeId        int32                  `protobuf:"varint,2,opt,name=type_id,json=typeId,proto3" json:"type_id,omitempty"`
+	BestBuy       float64                `protobuf:"fixed64,3,opt,name=best_buy,json=bestBuy,proto3" json:"best_buy,omitempty"`
+	BestSell      float64                `protobuf:"fixed64,4,opt,name=best_sell,json=bestSell,proto3" json:"best_sell,omitempty"`
+	Spread        float64                `protobuf:"fixed64,5,opt,name=spread,proto3" json:"spread,omitempty"`
+	BuyVolume     int64                  `protobuf:"varint,6,opt,name=buy_volume,json=buyVolume,proto3" json:"buy_volume,omitempty"`
+	SellVolume    int64                  `protobuf:"varint,7,opt,name=sell_volume,json=sellVolume,proto3" json:"sell_volume,omitempty"`
+	BuyOrders     int32                  `protobuf:"varint,8,opt,name=buy_orders,json=buyOrders,proto3" json:"buy_orders,omitempty"`
+	SellOrders    int32                  `protobuf:"varint,9,opt,name=sell_orders,json=sellOrders,proto3" json:"sell_orders,omitempty"`
+	TimeChanged   *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=time_changed,json=timeChanged,proto3" json:"time_changed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BookTop) Reset() {
+	*x = BookTop{}
+	mi := &file_marketwatch_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookTop) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookTop) ProtoMessage() {}
+
+func (x *BookTop) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookTop.ProtoReflect.Descriptor instead.
+func (*BookTop) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *BookTop) GetLocationId() int64 {
+	if x != nil {
+		return x.LocationId
+	}
+	return 0
+}
+
+func (x *BookTop) GetTypeId() int32 {
+	if x != nil {
+		return x.TypeId
+	}
+	return 0
+}
+
+func (x *BookTop) GetBestBuy() float64 {
+	if x != nil {
+		return x.BestBuy
+	}
+	return 0
+}
+
+func (x *BookTop) GetBestSell() float64 {
+	if x != nil {
+		return x.BestSell
+	}
+	return 0
+}
+
+func (x *BookTop) GetSpread() float64 {
+	if x != nil {
+		return x.Spread
+	}
+	return 0
+}
+
+func (x *BookTop) GetBuyVolume() int64 {
+	if x != nil {
+		return x.BuyVolume
+	}
+	return 0
+}
+
+func (x *BookTop) GetSellVolume() int64 {
+	if x != nil {
+		return x.SellVolume
+	}
+	return 0
+}
+
+func (x *BookTop) GetBuyOrders() int32 {
+	if x != nil {
+		return x.BuyOrders
+	}
+	return 0
+}
+
+func (x *BookTop) GetSellOrders() int32 {
+	if x != nil {
+		return x.SellOrders
+	}
+	return 0
+}
+
+func (x *BookTop) GetTimeChanged() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeChanged
+	}
+	return nil
+}
+
+type Trades struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*Trade               `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Trades) Reset() {
+	*x = Trades{}
+	mi := &file_marketwatch_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Trades) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Trades) ProtoMessage() {}
+
+func (x *Trades) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Trades.ProtoReflect.Descriptor instead.
+func (*Trades) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Trades) GetItems() []*Trade {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type Trade struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	OrderId    int64                  `protobuf:"varint,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	LocationId int64                  `protobuf:"varint,2,opt,name=location_id,json=locationId,proto3" json:"location_id,omitempty"`
+	RegionId   int64                  `protobuf:"varint,3,opt,name=region_id,json=regionId,proto3" json:"region_id,omitempty"`
+	TypeId     int32                  `protobuf:"varint,4,opt,name=type_id,json=typeId,proto3" json:"type_id,omitempty"`
+	Price      float64                `protobuf:"fixed64,5,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity   int32                  `protobuf:"varint,6,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// "buy" or "sell"
+	Side          string                 `protobuf:"bytes,7,opt,name=side,proto3" json:"side,omitempty"`
+	Filled        bool                   `protobuf:"varint,8,opt,name=filled,proto3" json:"filled,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Trade) Reset() {
+	*x = Trade{}
+	mi := &file_marketwatch_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Trade) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Trade) ProtoMessage() {}
+
+func (x *Trade) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Trade.ProtoReflect.Descriptor instead.
+func (*Trade) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Trade) GetOrderId() int64 {
+	if x != nil {
+		return x.OrderId
+	}
+	return 0
+}
+
+func (x *Trade) GetLocationId() int64 {
+	if x != nil {
+		return x.LocationId
+	}
+	return 0
+}
+
+func (x *Trade) GetRegionId() int64 {
+	if x != nil {
+		return x.RegionId
+	}
+	return 0
+}
+
+func (x *Trade) GetTypeId() int32 {
+	if x != nil {
+		return x.TypeId
+	}
+	return 0
+}
+
+func (x *Trade) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Trade) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *Trade) GetSide() string {
+	if x != nil {
+		return x.Side
+	}
+	return ""
+}
+
+func (x *Trade) GetFilled() bool {
+	if x != nil {
+		return x.Filled
+	}
+	return false
+}
+
+func (x *Trade) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+type Candles struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*Candle              `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Candles) Reset() {
+	*x = Candles{}
+	mi := &file_marketwatch_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Candles) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Candles) ProtoMessage() {}
+
+func (x *Candles) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Candles.ProtoReflect.Descriptor instead.
+func (*Candles) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Candles) GetItems() []*Candle {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type Candle struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "5m", "1h" or "1d"
+	Interval      string                 `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`
+	RegionId      int64                  `protobuf:"varint,2,opt,name=region_id,json=regionId,proto3" json:"region_id,omitempty"`
+	LocationId    int64                  `protobuf:"varint,3,opt,name=location_id,json=locationId,proto3" json:"location_id,omitempty"`
+	TypeId        int32                  `protobuf:"varint,4,opt,name=type_id,json=typeId,proto3" json:"type_id,omitempty"`
+	Start         *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=start,proto3" json:"start,omitempty"`
+	Open          float64                `protobuf:"fixed64,6,opt,name=open,proto3" json:"open,omitempty"`
+	High          float64                `protobuf:"fixed64,7,opt,name=high,proto3" json:"high,omitempty"`
+	Low           float64                `protobuf:"fixed64,8,opt,name=low,proto3" json:"low,omitempty"`
+	Close         float64                `protobuf:"fixed64,9,opt,name=close,proto3" json:"close,omitempty"`
+	Volume        int64                  `protobuf:"varint,10,opt,name=volume,proto3" json:"volume,omitempty"`
+	Trades        int32                  `protobuf:"varint,11,opt,name=trades,proto3" json:"trades,omitempty"`
+	Closed        bool                   `protobuf:"varint,12,opt,name=closed,proto3" json:"closed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Candle) Reset() {
+	*x = Candle{}
+	mi := &file_marketwatch_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Candle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Candle) ProtoMessage() {}
+
+func (x *Candle) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Candle.ProtoReflect.Descriptor instead.
+func (*Candle) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *Candle) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+func (x *Candle) GetRegionId() int64 {
+	if x != nil {
+		return x.RegionId
+	}
+	return 0
+}
+
+func (x *Candle) GetLocationId() int64 {
+	if x != nil {
+		return x.LocationId
+	}
+	return 0
+}
+
+func (x *Candle) GetTypeId() int32 {
+	if x != nil {
+		return x.TypeId
+	}
+	return 0
+}
+
+func (x *Candle) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *Candle) GetOpen() float64 {
+	if x != nil {
+		return x.Open
+	}
+	return 0
+}
+
+func (x *Candle) GetHigh() float64 {
+	if x != nil {
+		return x.High
+	}
+	return 0
+}
+
+func (x *Candle) GetLow() float64 {
+	if x != nil {
+		return x.Low
+	}
+	return 0
+}
+
+func (x *Candle) GetClose() float64 {
+	if x != nil {
+		return x.Close
+	}
+	return 0
+}
+
+func (x *Candle) GetVolume() int64 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+func (x *Candle) GetTrades() int32 {
+	if x != nil {
+		return x.Trades
+	}
+	return 0
+}
+
+func (x *Candle) GetClosed() bool {
+	if x != nil {
+		return x.Closed
+	}
+	return false
+}
+
+// Ack replies to a command with the resulting state of the connection
+type Ack struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Command       string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	Channels      []string               `protobuf:"bytes,3,rep,name=channels,proto3" json:"channels,omitempty"`
+	Filter        *Filter                `protobuf:"bytes,4,opt,name=filter,proto3" json:"filter,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	mi := &file_marketwatch_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Ack) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Ack) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *Ack) GetChannels() []string {
+	if x != nil {
+		return x.Channels
+	}
+	return nil
+}
+
+func (x *Ack) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *Ack) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Snapshot frames the dump sent on connect
+type Snapshot struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Broadcasts up to this sequence are included in the dump, later ones follow it
+	Seq      uint64   `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Channels []string `protobuf:"bytes,2,rep,name=channels,proto3" json:"channels,omitempty"`
+	// Messages and payload items in the dump, set on snapshotEnd
+	Messages int64 `protobuf:"varint,3,opt,name=messages,proto3" json:"messages,omitempty"`
+	Items    int64 `protobuf:"varint,4,opt,name=items,proto3" json:"items,omitempty"`
+	// The stream seq belongs to
+	Stream        string `protobuf:"bytes,5,opt,name=stream,proto3" json:"stream,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Snapshot) Reset() {
+	*x = Snapshot{}
+	mi := &file_marketwatch_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Snapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Snapshot) ProtoMessage() {}
+
+func (x *Snapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Snapshot.ProtoReflect.Descriptor instead.
+func (*Snapshot) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *Snapshot) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Snapshot) GetChannels() []string {
+	if x != nil {
+		return x.Channels
+	}
+	return nil
+}
+
+func (x *Snapshot) GetMessages() int64 {
+	if x != nil {
+		return x.Messages
+	}
+	return 0
+}
+
+func (x *Snapshot) GetItems() int64 {
+	if x != nil {
+		return x.Items
+	}
+	return 0
+}
+
+func (x *Snapshot) GetStream() string {
+	if x != nil {
+		return x.Stream
+	}
+	return ""
+}
+
+type Filter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Region        []int64                `protobuf:"varint,1,rep,packed,name=region,proto3" json:"region,omitempty"`
+	Location      []int64                `protobuf:"varint,2,rep,packed,name=location,proto3" json:"location,omitempty"`
+	Type          []int64                `protobuf:"varint,3,rep,packed,name=type,proto3" json:"type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Filter) Reset() {
+	*x = Filter{}
+	mi := &file_marketwatch_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Filter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Filter) ProtoMessage() {}
+
+func (x *Filter) ProtoReflect() protoreflect.Message {
+	mi := &file_marketwatch_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Filter.ProtoReflect.Descriptor instead.
+func (*Filter) Descriptor() ([]byte, []int) {
+	return file_marketwatch_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *Filter) GetRegion() []int64 {
+	if x != nil {
+		return x.Region
+	}
+	return nil
+}
+
+func (x *Filter) GetLocation() []int64 {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *Filter) GetType() []int64 {
+	if x != nil {
+		return x.Type
+	}
+	return nil
+}
+
+var File_marketwatch_proto protoreflect.FileDescriptor
+
+const file_marketwatch_proto_rawDesc = "" +
+	"\n" +
+	"\x11marketwatch.proto\x12\x0emarketwatch.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"d\n" +
+	"\rStreamRequest\x12.\n" +
+	"\x06filter\x18\x01 \x01(\v2\x16.marketwatch.v1.FilterR\x06filter\x12#\n" +
+	"\rskip_snapshot\x18\x02 \x01(\bR\fskipSnapshot\"t\n" +
+	"\x13GetOrderBookRequest\x12\x1f\n" +
+	"\vlocation_id\x18\x01 \x01(\x03R\n" +
+	"locationId\x12\x17\n" +
+	"\atype_id\x18\x02 \x01(\x05R\x06typeId\x12\x19\n" +
+	"\x05depth\x18\x03 \x01(\x05H\x00R\x05depth\x88\x01\x01B\b\n" +
+	"\x06_depth\"\x94\x01\n" +
+	"\tOrderBook\x12)\n" +
+	"\x03top\x18\x01 \x01(\v2\x17.marketwatch.v1.BookTopR\x03top\x12,\n" +
+	"\x03buy\x18\x02 \x03(\v2\x1a.marketwatch.v1.PriceLevelR\x03buy\x12.\n" +
+	"\x04sell\x18\x03 \x03(\v2\x1a.marketwatch.v1.PriceLevelR\x04sell\"R\n" +
+	"\n" +
+	"PriceLevel\x12\x14\n" +
+	"\x05price\x18\x01 \x01(\x01R\x05price\x12\x16\n" +
+	"\x06volume\x18\x02 \x01(\x03R\x06volume\x12\x16\n" +
+	"\x06orders\x18\x03 \x01(\x05R\x06orders\"5\n" +
+	"\x12GetContractRequest\x12\x1f\n" +
+	"\vcontract_id\x18\x01 \x01(\x05R\n" +
+	"contractId\"\xc9\x05\n" +
+	"\x05Frame\x12%\n" +
+	"\x0eschema_version\x18\x01 \x01(\rR\rschemaVersion\x12\x16\n" +
+	"\x06action\x18\x02 \x01(\tR\x06action\x12\x10\n" +
+	"\x03seq\x18\x03 \x01(\x04R\x03seq\x12\x1b\n" +
+	"\tregion_id\x18\x04 \x01(\x03R\bregionId\x12.\n" +
+	"\x04time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x16\n" +
+	"\x06stream\x18\x06 \x01(\tR\x06stream\x120\n" +
+	"\x06orders\x18\n" +
+	" \x01(\v2\x16.marketwatch.v1.OrdersH\x00R\x06orders\x12C\n" +
+	"\rorder_changes\x18\v \x01(\v2\x1c.marketwatch.v1.OrderChangesH\x00R\forderChanges\x129\n" +
+	"\tcontracts\x18\f \x01(\v2\x19.marketwatch.v1.ContractsH\x00R\tcontracts\x12L\n" +
+	"\x10contract_changes\x18\r \x01(\v2\x1f.marketwatch.v1.ContractChangesH\x00R\x0fcontractChanges\x127\n" +
+	"\tbook_tops\x18\x0e \x01(\v2\x18.marketwatch.v1.BookTopsH\x00R\bbookTops\x120\n" +
+	"\x06trades\x18\x0f \x01(\v2\x16.marketwatch.v1.TradesH\x00R\x06trades\x123\n" +
+	"\acandles\x18\x10 \x01(\v2\x17.marketwatch.v1.CandlesH\x00R\acandles\x12'\n" +
+	"\x03ack\x18\x11 \x01(\v2\x13.marketwatch.v1.AckH\x00R\x03ack\x126\n" +
+	"\bsnapshot\x18\x12 \x01(\v2\x18.marketwatch.v1.SnapshotH\x00R\bsnapshotB\t\n" +
+	"\apayload\"5\n" +
+	"\x06Orders\x12+\n" +
+	"\x05items\x18\x01 \x03(\v2\x15.marketwatch.v1.OrderR\x05items\"\xfe\x02\n" +
+	"\x05Order\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\x03R\aorderId\x12\x1f\n" +
+	"\vlocation_id\x18\x02 \x01(\x03R\n" +
+	"locationId\x12\x1b\n" +
+	"\tsystem_id\x18\x03 \x01(\x05R\bsystemId\x12\x17\n" +
+	"\atype_id\x18\x04 \x01(\x05R\x06typeId\x12 \n" +
+	"\fis_buy_order\x18\x05 \x01(\bR\n" +
+	"isBuyOrder\x12\x14\n" +
+	"\x05price\x18\x06 \x01(\x01R\x05price\x12#\n" +
+	"\rvolume_remain\x18\a \x01(\x05R\fvolumeRemain\x12!\n" +
+	"\fvolume_total\x18\b \x01(\x05R\vvolumeTotal\x12\x1d\n" +
+	"\n" +
+	"min_volume\x18\t \x01(\x05R\tminVolume\x12\x14\n" +
+	"\x05range\x18\n" +
+	" \x01(\tR\x05range\x12\x1a\n" +
+	"\bduration\x18\v \x01(\x05R\bduration\x122\n" +
+	"\x06issued\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\x06issued\"A\n" +
+	"\fOrderChanges\x121\n" +
+	"\x05items\x18\x01 \x03(\v2\x1b.marketwatch.v1.OrderChangeR\x05items\"\xf3\x02\n" +
+	"\vOrderChange\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\x03R\aorderId\x12\x1f\n" +
+	"\vlocation_id\x18\x02 \x01(\x03R\n" +
+	"locationId\x12\x17\n" +
+	"\atype_id\x18\x03 \x01(\x05R\x06typeId\x12#\n" +
+	"\rvolume_change\x18\x04 \x01(\x05R\fvolumeChange\x12#\n" +
+	"\rvolume_remain\x18\x05 \x01(\x05R\fvolumeRemain\x12\x14\n" +
+	"\x05price\x18\x06 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bduration\x18\a \x01(\x05R\bduration\x12 \n" +
+	"\fis_buy_order\x18\b \x01(\bR\n" +
+	"isBuyOrder\x122\n" +
+	"\x06issued\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x06issued\x12=\n" +
+	"\ftime_changed\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\vtimeChanged\"?\n" +
+	"\tContracts\x122\n" +
+	"\x05items\x18\x01 \x03(\v2\x1c.marketwatch.v1.FullContractR\x05items\"\xa9\x01\n" +
+	"\fFullContract\x124\n" +
+	"\bcontract\x18\x01 \x01(\v2\x18.marketwatch.v1.ContractR\bcontract\x122\n" +
+	"\x05items\x18\x02 \x03(\v2\x1c.marketwatch.v1.ContractItemR\x05items\x12/\n" +
+	"\x04bids\x18\x03 \x03(\v2\x1b.marketwatch.v1.ContractBidR\x04bids\"\xc7\x04\n" +
+	"\bContract\x12\x1f\n" +
+	"\vcontract_id\x18\x01 \x01(\x05R\n" +
+	"contractId\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x1b\n" +
+	"\tissuer_id\x18\x03 \x01(\x05R\bissuerId\x122\n" +
+	"\x15issuer_corporation_id\x18\x04 \x01(\x05R\x13issuerCorporationId\x12'\n" +
+	"\x0ffor_corporation\x18\x05 \x01(\bR\x0eforCorporation\x12*\n" +
+	"\x11start_location_id\x18\x06 \x01(\x03R\x0fstartLocationId\x12&\n" +
+	"\x0fend_location_id\x18\a \x01(\x03R\rendLocationId\x12\x14\n" +
+	"\x05title\x18\b \x01(\tR\x05title\x12\x14\n" +
+	"\x05price\x18\t \x01(\x01R\x05price\x12\x16\n" +
+	"\x06buyout\x18\n" +
+	" \x01(\x01R\x06buyout\x12\x16\n" +
+	"\x06reward\x18\v \x01(\x01R\x06reward\x12\x1e\n" +
+	"\n" +
+	"collateral\x18\f \x01(\x01R\n" +
+	"collateral\x12\x16\n" +
+	"\x06volume\x18\r \x01(\x01R\x06volume\x12(\n" +
+	"\x10days_to_complete\x18\x0e \x01(\x05R\x0edaysToComplete\x12;\n" +
+	"\vdate_issued\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"dateIssued\x12=\n" +
+	"\fdate_expired\x18\x10 \x01(\v2\x1a.google.protobuf.TimestampR\vdateExpired\"\xb4\x02\n" +
+	"\fContractItem\x12\x1b\n" +
+	"\trecord_id\x18\x01 \x01(\x03R\brecordId\x12\x17\n" +
+	"\aitem_id\x18\x02 \x01(\x03R\x06itemId\x12\x17\n" +
+	"\atype_id\x18\x03 \x01(\x05R\x06typeId\x12\x1a\n" +
+	"\bquantity\x18\x04 \x01(\x05R\bquantity\x12\x1f\n" +
+	"\vis_included\x18\x05 \x01(\bR\n" +
+	"isIncluded\x12*\n" +
+	"\x11is_blueprint_copy\x18\x06 \x01(\bR\x0fisBlueprintCopy\x12/\n" +
+	"\x13material_efficiency\x18\a \x01(\x05R\x12materialEfficiency\x12'\n" +
+	"\x0ftime_efficiency\x18\b \x01(\x05R\x0etimeEfficiency\x12\x12\n" +
+	"\x04runs\x18\t \x01(\x05R\x04runs\"s\n" +
+	"\vContractBid\x12\x15\n" +
+	"\x06bid_id\x18\x01 \x01(\x05R\x05bidId\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x02R\x06amount\x125\n" +
+	"\bdate_bid\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\adateBid\"G\n" +
+	"\x0fContractChanges\x124\n" +
+	"\x05items\x18\x01 \x03(\v2\x1e.marketwatch.v1.ContractChangeR\x05items\"\xc5\x02\n" +
+	"\x0eContractChange\x12\x1f\n" +
+	"\vcontract_id\x18\x01 \x01(\x05R\n" +
+	"contractId\x12\x1f\n" +
+	"\vlocation_id\x18\x02 \x01(\x03R\n" +
+	"locationId\x12\x18\n" +
+	"\aexpired\x18\x03 \x01(\bR\aexpired\x12=\n" +
+	"\fdate_expired\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\vdateExpired\x12/\n" +
+	"\x04bids\x18\x05 \x03(\v2\x1b.marketwatch.v1.ContractBidR\x04bids\x12\x14\n" +
+	"\x05price\x18\x06 \x01(\x01R\x05price\x12\x12\n" +
+	"\x04type\x18\a \x01(\tR\x04type\x12=\n" +
+	"\ftime_changed\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\vtimeChanged\"9\n" +
+	"\bBookTops\x12-\n" +
+	"\x05items\x18\x01 \x03(\v2\x17.marketwatch.v1.BookTopR\x05items\"\xd2\x02\n" +
+	"\aBookTop\x12\x1f\n" +
+	"\vlocation_id\x18\x01 \x01(\x03R\n" +
+	"locationId\x12\x17\n" +
+	"\atype_id\x18\x02 \x01(\x05R\x06typeId\x12\x19\n" +
+	"\bbest_buy\x18\x03 \x01(\x01R\abestBuy\x12\x1b\n" +
+	"\tbest_sell\x18\x04 \x01(\x01R\bbestSell\x12\x16\n" +
+	"\x06spread\x18\x05 \x01(\x01R\x06spread\x12\x1d\n" +
+	"\n" +
+	"buy_volume\x18\x06 \x01(\x03R\tbuyVolume\x12\x1f\n" +
+	"\vsell_volume\x18\a \x01(\x03R\n" +
+	"sellVolume\x12\x1d\n" +
+	"\n" +
+	"buy_orders\x18\b \x01(\x05R\tbuyOrders\x12\x1f\n" +
+	"\vsell_orders\x18\t \x01(\x05R\n" +
+	"sellOrders\x12=\n" +
+	"\ftime_changed\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\vtimeChanged\"5\n" +
+	"\x06Trades\x12+\n" +
+	"\x05items\x18\x01 \x03(\v2\x15.marketwatch.v1.TradeR\x05items\"\x87\x02\n" +
+	"\x05Trade\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\x03R\aorderId\x12\x1f\n" +
+	"\vlocation_id\x18\x02 \x01(\x03R\n" +
+	"locationId\x12\x1b\n" +
+	"\tregion_id\x18\x03 \x01(\x03R\bregionId\x12\x17\n" +
+	"\atype_id\x18\x04 \x01(\x05R\x06typeId\x12\x14\n" +
+	"\x05price\x18\x05 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bquantity\x18\x06 \x01(\x05R\bquantity\x12\x12\n" +
+	"\x04side\x18\a \x01(\tR\x04side\x12\x16\n" +
+	"\x06filled\x18\b \x01(\bR\x06filled\x12.\n" +
+	"\x04time\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x04time\"7\n" +
+	"\aCandles\x12,\n" +
+	"\x05items\x18\x01 \x03(\v2\x16.marketwatch.v1.CandleR\x05items\"\xc5\x02\n" +
+	"\x06Candle\x12\x1a\n" +
+	"\binterval\x18\x01 \x01(\tR\binterval\x12\x1b\n" +
+	"\tregion_id\x18\x02 \x01(\x03R\bregionId\x12\x1f\n" +
+	"\vlocation_id\x18\x03 \x01(\x03R\n" +
+	"locationId\x12\x17\n" +
+	"\atype_id\x18\x04 \x01(\x05R\x06typeId\x120\n" +
+	"\x05start\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12\x12\n" +
+	"\x04open\x18\x06 \x01(\x01R\x04open\x12\x12\n" +
+	"\x04high\x18\a \x01(\x01R\x04high\x12\x10\n" +
+	"\x03low\x18\b \x01(\x01R\x03low\x12\x14\n" +
+	"\x05close\x18\t \x01(\x01R\x05close\x12\x16\n" +
+	"\x06volume\x18\n" +
+	" \x01(\x03R\x06volume\x12\x16\n" +
+	"\x06trades\x18\v \x01(\x05R\x06trades\x12\x16\n" +
+	"\x06closed\x18\f \x01(\bR\x06closed\"\x91\x01\n" +
+	"\x03Ack\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\acommand\x18\x02 \x01(\tR\acommand\x12\x1a\n" +
+	"\bchannels\x18\x03 \x03(\tR\bchannels\x12.\n" +
+	"\x06filter\x18\x04 \x01(\v2\x16.marketwatch.v1.FilterR\x06filter\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"\x82\x01\n" +
+	"\bSnapshot\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12\x1a\n" +
+	"\bchannels\x18\x02 \x03(\tR\bchannels\x12\x1a\n" +
+	"\bmessages\x18\x03 \x01(\x03R\bmessages\x12\x14\n" +
+	"\x05items\x18\x04 \x01(\x03R\x05items\x12\x16\n" +
+	"\x06stream\x18\x05 \x01(\tR\x06stream\"P\n" +
+	"\x06Filter\x12\x16\n" +
+	"\x06region\x18\x01 \x03(\x03R\x06region\x12\x1a\n" +
+	"\blocation\x18\x02 \x03(\x03R\blocation\x12\x12\n" +
+	"\x04type\x18\x03 \x03(\x03R\x04type2\xc1\x02\n" +
+	"\vMarketWatch\x12F\n" +
+	"\fStreamOrders\x12\x1d.marketwatch.v1.StreamRequest\x1a\x15.marketwatch.v1.Frame0\x01\x12I\n" +
+	"\x0fStreamContracts\x12\x1d.marketwatch.v1.StreamRequest\x1a\x15.marketwatch.v1.Frame0\x01\x12N\n" +
+	"\fGetOrderBook\x12#.marketwatch.v1.GetOrderBookRequest\x1a\x19.marketwatch.v1.OrderBook\x12O\n" +
+	"\vGetContract\x12\".marketwatch.v1.GetContractRequest\x1a\x1c.marketwatch.v1.FullContractB2Z0github.com/antihax/eve-marketwatch/schema;schemab\x06proto3"
+
+var (
+	file_marketwatch_proto_rawDescOnce sync.Once
+	file_marketwatch_proto_rawDescData []byte
+)
+
+func file_marketwatch_proto_rawDescGZIP() []byte {
+	file_marketwatch_proto_rawDescOnce.Do(func() {
+		file_marketwatch_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_marketwatch_proto_rawDesc), len(file_marketwatch_proto_rawDesc)))
+	})
+	return file_marketwatch_proto_rawDescData
+}
+
+var file_marketwatch_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_marketwatch_proto_goTypes = []any{
+	(*StreamRequest)(nil),         // 0: marketwatch.v1.StreamRequest
+	(*GetOrderBookRequest)(nil),   // 1: marketwatch.v1.GetOrderBookRequest
+	(*OrderBook)(nil),             // 2: marketwatch.v1.OrderBook
+	(*PriceLevel)(nil),            // 3: marketwatch.v1.PriceLevel
+	(*GetContractRequest)(nil),    // 4: marketwatch.v1.GetContractRequest
+	(*Frame)(nil),                 // 5: marketwatch.v1.Frame
+	(*Orders)(nil),                // 6: marketwatch.v1.Orders
+	(*Order)(nil),                 // 7: marketwatch.v1.Order
+	(*OrderChanges)(nil),          // 8: marketwatch.v1.OrderChanges
+	(*OrderChange)(nil),           // 9: marketwatch.v1.OrderChange
+	(*Contracts)(nil),             // 10: marketwatch.v1.Contracts
+	(*FullContract)(nil),          // 11: marketwatch.v1.FullContract
+	(*Contract)(nil),              // 12: marketwatch.v1.Contract
+	(*ContractItem)(nil),          // 13: marketwatch.v1.ContractItem
+	(*ContractBid)(nil),           // 14: marketwatch.v1.ContractBid
+	(*ContractChanges)(nil),       // 15: marketwatch.v1.ContractChanges
+	(*ContractChange)(nil),        // 16: marketwatch.v1.ContractChange
+	(*BookTops)(nil),              // 17: marketwatch.v1.BookTops
+	(*BookTop)(nil),               // 18: marketwatch.v1.BookTop
+	(*Trades)(nil),                // 19: marketwatch.v1.Trades
+	(*Trade)(nil),                 // 20: marketwatch.v1.Trade
+	(*Candles)(nil),               // 21: marketwatch.v1.Candles
+	(*Candle)(nil),                // 22: marketwatch.v1.Candle
+	(*Ack)(nil),                   // 23: marketwatch.v1.Ack
+	(*Snapshot)(nil),              // 24: marketwatch.v1.Snapshot
+	(*Filter)(nil),                // 25: marketwatch.v1.Filter
+	(*timestamppb.Timestamp)(nil), // 26: google.protobuf.Timestamp
+}
+var file_marketwatch_proto_depIdxs = []int32{
+	25, // 0: marketwatch.v1.StreamRequest.filter:type_name -> marketwatch.v1.Filter
+	18, // 1: marketwatch.v1.OrderBook.top:type_name -> marketwatch.v1.BookTop
+	3,  // 2: marketwatch.v1.OrderBook.buy:type_name -> marketwatch.v1.PriceLevel
+	3,  // 3: marketwatch.v1.OrderBook.sell:type_name -> marketwatch.v1.PriceLevel
+	26, // 4: marketwatch.v1.Frame.time:type_name -> google.protobuf.Timestamp
+	6,  // 5: marketwatch.v1.Frame.orders:type_name -> marketwatch.v1.Orders
+	8,  // 6: marketwatch.v1.Frame.order_changes:type_name -> marketwatch.v1.OrderChanges
+	10, // 7: marketwatch.v1.Frame.contracts:type_name -> marketwatch.v1.Contracts
+	15, // 8: marketwatch.v1.Frame.contract_changes:type_name -> marketwatch.v1.ContractChanges
+	17, // 9: marketwatch.v1.Frame.book_tops:type_name -> marketwatch.v1.BookTops
+	19, // 10: marketwatch.v1.Frame.trades:type_name -> marketwatch.v1.Trades
+	21, // 11: marketwatch.v1.Frame.candles:type_name -> marketwatch.v1.Candles
+	23, // 12: marketwatch.v1.Frame.ack:type_name -> marketwatch.v1.Ack
+	24, // 13: marketwatch.v1.Frame.snapshot:type_name -> marketwatch.v1.Snapshot
+	7,  // 14: marketwatch.v1.Orders.items:type_name -> marketwatch.v1.Order
+	26, // 15: marketwatch.v1.Order.issued:type_name -> google.protobuf.Timestamp
+	9,  // 16: marketwatch.v1.OrderChanges.items:type_name -> marketwatch.v1.OrderChange
+	26, // 17: marketwatch.v1.OrderChange.issued:type_name -> google.protobuf.Timestamp
+	26, // 18: marketwatch.v1.OrderChange.time_changed:type_name -> google.protobuf.Timestamp
+	11, // 19: marketwatch.v1.Contracts.items:type_name -> marketwatch.v1.FullContract
+	12, // 20: marketwatch.v1.FullContract.contract:type_name -> marketwatch.v1.Contract
+	13, // 21: marketwatch.v1.FullContract.items:type_name -> marketwatch.v1.ContractItem
+	14, // 22: marketwatch.v1.FullContract.bids:type_name -> marketwatch.v1.ContractBid
+	26, // 23: marketwatch.v1.Contract.date_issued:type_name -> google.protobuf.Timestamp
+	26, // 24: marketwatch.v1.Contract.date_expired:type_name -> google.protobuf.Timestamp
+	26, // 25: marketwatch.v1.ContractBid.date_bid:type_name -> google.protobuf.Timestamp
+	16, // 26: marketwatch.v1.ContractChanges.items:type_name -> marketwatch.v1.ContractChange
+	26, // 27: marketwatch.v1.ContractChange.date_expired:type_name -> google.protobuf.Timestamp
+	14, // 28: marketwatch.v1.ContractChange.bids:type_name -> marketwatch.v1.ContractBid
+	26, // 29: marketwatch.v1.ContractChange.time_changed:type_name -> google.protobuf.Timestamp
+	18, // 30: marketwatch.v1.BookTops.items:type_name -> marketwatch.v1.BookTop
+	26, // 31: marketwatch.v1.BookTop.time_changed:type_name -> google.protobuf.Timestamp
+	20, // 32: marketwatch.v1.Trades.items:type_name -> marketwatch.v1.Trade
+	26, // 33: marketwatch.v1.Trade.time:type_name -> google.protobuf.Timestamp
+	22, // 34: marketwatch.v1.Candles.items:type_name -> marketwatch.v1.Candle
+	26, // 35: marketwatch.v1.Candle.start:type_name -> google.protobuf.Timestamp
+	25, // 36: marketwatch.v1.Ack.filter:type_name -> marketwatch.v1.Filter
+	0,  // 37: marketwatch.v1.MarketWatch.StreamOrders:input_type -> marketwatch.v1.StreamRequest
+	0,  // 38: marketwatch.v1.MarketWatch.StreamContracts:input_type -> marketwatch.v1.StreamRequest
+	1,  // 39: marketwatch.v1.MarketWatch.GetOrderBook:input_type -> marketwatch.v1.GetOrderBookRequest
+	4,  // 40: marketwatch.v1.MarketWatch.GetContract:input_type -> marketwatch.v1.GetContractRequest
+	5,  // 41: marketwatch.v1.MarketWatch.StreamOrders:output_type -> marketwatch.v1.Frame
+	5,  // 42: marketwatch.v1.MarketWatch.StreamContracts:output_type -> marketwatch.v1.Frame
+	2,  // 43: marketwatch.v1.MarketWatch.GetOrderBook:output_type -> marketwatch.v1.OrderBook
+	11, // 44: marketwatch.v1.MarketWatch.GetContract:output_type -> marketwatch.v1.FullContract
+	41, // [41:45] is the sub-list for method output_type
+	37, // [37:41] is the sub-list for method input_type
+	37, // [37:37] is the sub-list for extension type_name
+	37, // [37:37] is the sub-list for extension extendee
+	0,  // [0:37] is the sub-list for field type_name
+}
+
+func init() { file_marketwatch_proto_init() }
+func file_marketwatch_proto_init() {
+	if File_marketwatch_proto != nil {
+		return
+	}
+	file_marketwatch_proto_msgTypes[1].OneofWrappers = []any{}
+	file_marketwatch_proto_msgTypes[5].OneofWrappers = []any{
+		(*Frame_Orders)(nil),
+		(*Frame_OrderChanges)(nil),
+		(*Frame_Contracts)(nil),
+		(*Frame_ContractChanges)(nil),
+		(*Frame_BookTops)(nil),
+		(*Frame_Trades)(nil),
+		(*Frame_Candles)(nil),
+		(*Frame_Ack)(nil),
+		(*Frame_Snapshot)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_marketwatch_proto_rawDesc), len(file_marketwatch_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   26,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_marketwatch_proto_goTypes,
+		DependencyIndexes: file_marketwatch_proto_depIdxs,
+		MessageInfos:      file_marketwatch_proto_msgTypes,
+	}.Build()
+	File_marketwatch_proto = out.File
+	file_marketwatch_proto_goTypes = nil
+	file_marketwatch_proto_depIdxs = nil
+}
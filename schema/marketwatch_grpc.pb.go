@@ -0,0 +1,269 @@
+// Protobuf encoding of the websocket messages, selected with ?encoding=protobuf
+// or the "protobuf" websocket subprotocol. Each binary frame is one Frame.
+// The gRPC service streams the same frames.
+//
+// Field numbers are never reused. schema_version follows the JSON Schema in
+// marketwatch.schema.json and is bumped on any breaking change.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: marketwatch.proto
+
+package schema
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MarketWatch_StreamOrders_FullMethodName    = "/marketwatch.v1.MarketWatch/StreamOrders"
+	MarketWatch_StreamContracts_FullMethodName = "/marketwatch.v1.MarketWatch/StreamContracts"
+	MarketWatch_GetOrderBook_FullMethodName    = "/marketwatch.v1.MarketWatch/GetOrderBook"
+	MarketWatch_GetContract_FullMethodName     = "/marketwatch.v1.MarketWatch/GetContract"
+)
+
+// MarketWatchClient is the client API for MarketWatch service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// MarketWatch serves the same data as the websocket to gRPC clients. When API
+// keys are configured send one as "authorization: Bearer <key>" or "x-api-key"
+// metadata.
+type MarketWatchClient interface {
+	// The current orders matching the filter framed by snapshotBegin and
+	// snapshotEnd, then each addition, change and deletion as it is found.
+	StreamOrders(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Frame], error)
+	// The current contracts matching the filter framed by snapshotBegin and
+	// snapshotEnd, then each contractAddition, contractChange and contractDeletion.
+	StreamContracts(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Frame], error)
+	// The order book for a type at a location
+	GetOrderBook(ctx context.Context, in *GetOrderBookRequest, opts ...grpc.CallOption) (*OrderBook, error)
+	// A contract with its items and bids
+	GetContract(ctx context.Context, in *GetContractRequest, opts ...grpc.CallOption) (*FullContract, error)
+}
+
+type marketWatchClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMarketWatchClient(cc grpc.ClientConnInterface) MarketWatchClient {
+	return &marketWatchClient{cc}
+}
+
+func (c *marketWatchClient) StreamOrders(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Frame], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MarketWatch_ServiceDesc.Streams[0], MarketWatch_StreamOrders_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamRequest, Frame]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MarketWatch_StreamOrdersClient = grpc.ServerStreamingClient[Frame]
+
+func (c *marketWatchClient) StreamContracts(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Frame], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MarketWatch_ServiceDesc.Streams[1], MarketWatch_StreamContracts_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamRequest, Frame]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MarketWatch_StreamContractsClient = grpc.ServerStreamingClient[Frame]
+
+func (c *marketWatchClient) GetOrderBook(ctx context.Context, in *GetOrderBookRequest, opts ...grpc.CallOption) (*OrderBook, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderBook)
+	err := c.cc.Invoke(ctx, MarketWatch_GetOrderBook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketWatchClient) GetContract(ctx context.Context, in *GetContractRequest, opts ...grpc.CallOption) (*FullContract, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FullContract)
+	err := c.cc.Invoke(ctx, MarketWatch_GetContract_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarketWatchServer is the server API for MarketWatch service.
+// All implementations must embed UnimplementedMarketWatchServer
+// for forward compatibility.
+//
+// MarketWatch serves the same data as the websocket to gRPC clients. When API
+// keys are configured send one as "authorization: Bearer <key>" or "x-api-key"
+// metadata.
+type MarketWatchServer interface {
+	// The current orders matching the filter framed by snapshotBegin and
+	// snapshotEnd, then each addition, change and deletion as it is found.
+	StreamOrders(*StreamRequest, grpc.ServerStreamingServer[Frame]) error
+	// The current contracts matching the filter framed by snapshotBegin and
+	// snapshotEnd, then each contractAddition, contractChange and contractDeletion.
+	StreamContracts(*StreamRequest, grpc.ServerStreamingServer[Frame]) error
+	// The order book for a type at a location
+	GetOrderBook(context.Context, *GetOrderBookRequest) (*OrderBook, error)
+	// A contract with its items and bids
+	GetContract(context.Context, *GetContractRequest) (*FullContract, error)
+	mustEmbedUnimplementedMarketWatchServer()
+}
+
+// UnimplementedMarketWatchServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMarketWatchServer struct{}
+
+func (UnimplementedMarketWatchServer) StreamOrders(*StreamRequest, grpc.ServerStreamingServer[Frame]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamOrders not implemented")
+}
+func (UnimplementedMarketWatchServer) StreamContracts(*StreamRequest, grpc.ServerStreamingServer[Frame]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamContracts not implemented")
+}
+func (UnimplementedMarketWatchServer) GetOrderBook(context.Context, *GetOrderBookRequest) (*OrderBook, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderBook not implemented")
+}
+func (UnimplementedMarketWatchServer) GetContract(context.Context, *GetContractRequest) (*FullContract, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetContract not implemented")
+}
+func (UnimplementedMarketWatchServer) mustEmbedUnimplementedMarketWatchServer() {}
+func (UnimplementedMarketWatchServer) testEmbeddedByValue()                     {}
+
+// UnsafeMarketWatchServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MarketWatchServer will
+// result in compilation errors.
+type UnsafeMarketWatchServer interface {
+	mustEmbedUnimplementedMarketWatchServer()
+}
+
+func RegisterMarketWatchServer(s grpc.ServiceRegistrar, srv MarketWatchServer) {
+	// If the following call pancis, it indicates UnimplementedMarketWatchServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MarketWatch_ServiceDesc, srv)
+}
+
+func _MarketWatch_StreamOrders_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MarketWatchServer).StreamOrders(m, &grpc.GenericServerStream[StreamRequest, Frame]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MarketWatch_StreamOrdersServer = grpc.ServerStreamingServer[Frame]
+
+func _MarketWatch_StreamContracts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MarketWatchServer).StreamContracts(m, &grpc.GenericServerStream[StreamRequest, Frame]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MarketWatch_StreamContractsServer = grpc.ServerStreamingServer[Frame]
+
+func _MarketWatch_GetOrderBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketWatchServer).GetOrderBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketWatch_GetOrderBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketWatchServer).GetOrderBook(ctx, req.(*GetOrderBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketWatch_GetContract_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetContractRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketWatchServer).GetContract(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketWatch_GetContract_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketWatchServer).GetContract(ctx, req.(*GetContractRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MarketWatch_ServiceDesc is the grpc.ServiceDesc for MarketWatch service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MarketWatch_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "marketwatch.v1.MarketWatch",
+	HandlerType: (*MarketWatchServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetOrderBook",
+			Handler:    _MarketWatch_GetOrderBook_Handler,
+		},
+		{
+			MethodName: "GetContract",
+			Handler:    _MarketWatch_GetContract_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamOrders",
+			Handler:       _MarketWatch_StreamOrders_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamContracts",
+			Handler:       _MarketWatch_StreamContracts_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "marketwatch.proto",
+}
@@ -0,0 +1,6 @@
+// Package schema holds the published message formats: the JSON Schema for the
+// websocket frames, and the protobuf types and gRPC service generated from
+// marketwatch.proto.
+package schema
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative marketwatch.proto
@@ -0,0 +1,200 @@
+package wsbroadcast
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// APIKey lets a client connect when keys are configured.
+// Clients send it as "Authorization: Bearer <key>", "X-API-Key: <key>"
+// or ?key=<key> for browsers that cannot set headers.
+type APIKey struct {
+	// Name used in logs
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+
+	// Channels the key may subscribe to, all of them when empty
+	Channels []string `yaml:"channels"`
+
+	// Receive Private messages, such as markets in structures that need an
+	// authenticated token to see
+	Private bool `yaml:"private"`
+
+	// Connections open at once with the key, unlimited when zero
+	MaxConnections int `yaml:"max_connections"`
+}
+
+// Private messages are only sent to clients whose key allows them.
+// Everyone receives them when no keys are configured.
+type Private interface {
+	Private() bool
+}
+
+// keys tracks the configured API keys and the connections open with each
+type keys struct {
+	mutex       sync.Mutex
+	list        []APIKey
+	connections map[string]int
+}
+
+var (
+	errNoKey        = errors.New("an API key is required")
+	errUnknownKey   = errors.New("unknown API key")
+	errTooManyConns = errors.New("too many connections for this API key")
+)
+
+// Authenticate checks the key sent with a request to a handler served alongside
+// the hub, replying with an error if it is missing or unknown. The key is nil
+// without keys configured. Connection limits only apply to websockets.
+func (h *Hub) Authenticate(w http.ResponseWriter, r *http.Request) (*APIKey, bool) {
+	key, err := h.keys.find(r)
+	if err != nil {
+		authError(w, err)
+		return nil, false
+	}
+	return key, true
+}
+
+// LookupKey finds a key sent some other way than an HTTP request, such as
+// gRPC metadata. The key is nil without keys configured.
+func (h *Hub) LookupKey(sent string) (*APIKey, error) {
+	return h.keys.lookup(sent)
+}
+
+// authenticate finds the key sent with a request and takes one of its connections.
+// Returns nil without keys configured. Release the key when the client is done.
+func (k *keys) authenticate(r *http.Request) (*APIKey, error) {
+	key, err := k.find(r)
+	if key == nil || err != nil {
+		return nil, err
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if key.MaxConnections > 0 && k.connections[key.Key] >= key.MaxConnections {
+		return nil, errTooManyConns
+	}
+	k.connections[key.Key]++
+	return key, nil
+}
+
+// find the key sent with a request, nil without keys configured
+func (k *keys) find(r *http.Request) (*APIKey, error) {
+	return k.lookup(requestKey(r))
+}
+
+// lookup a key, nil without keys configured
+func (k *keys) lookup(sent string) (*APIKey, error) {
+	if len(k.list) == 0 {
+		return nil, nil
+	}
+	if sent == "" {
+		return nil, errNoKey
+	}
+
+	// Check every key in constant time so the comparison leaks nothing
+	var key *APIKey
+	for i := range k.list {
+		if subtle.ConstantTimeCompare([]byte(k.list[i].Key), []byte(sent)) == 1 {
+			key = &k.list[i]
+		}
+	}
+	if key == nil {
+		return nil, errUnknownKey
+	}
+	return key, nil
+}
+
+// release a connection taken by authenticate
+func (k *keys) release(key *APIKey) {
+	if key == nil {
+		return
+	}
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.connections[key.Key]--
+}
+
+// requestKey reads the key from the headers or the query
+func requestKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("key")
+}
+
+// authError replies to a request that failed authentication
+func authError(w http.ResponseWriter, err error) {
+	switch err {
+	case errTooManyConns:
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="marketwatch"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	}
+}
+
+// AllowChannel checks a key may subscribe to a channel. Any key may without keys configured.
+func (k *APIKey) AllowChannel(channel string) bool {
+	if k == nil || len(k.Channels) == 0 {
+		return true
+	}
+	for _, c := range k.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// allowMessage checks a client may receive a message
+func (c *Client) allowMessage(m interface{}) bool {
+	if c.key == nil || c.key.Private {
+		return true
+	}
+	p, ok := m.(Private)
+	return !ok || !p.Private()
+}
+
+// checkOrigin allows browsers from the configured origins, or any without a list
+func (c *Config) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(c.AllowedOrigins) == 0 || origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) || strings.EqualFold(allowed, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateKeys checks the keys are usable
+func validateKeys(list []APIKey) error {
+	seen := make(map[string]bool)
+	for _, k := range list {
+		switch {
+		case k.Key == "":
+			return fmt.Errorf("websocket key %q has no key", k.Name)
+		case seen[k.Key]:
+			return fmt.Errorf("websocket key %q is a duplicate", k.Name)
+		case k.MaxConnections < 0:
+			return fmt.Errorf("websocket key %q max_connections cannot be negative", k.Name)
+		}
+		seen[k.Key] = true
+	}
+	return nil
+}
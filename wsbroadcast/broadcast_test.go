@@ -1,10 +1,18 @@
 package wsbroadcast
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
@@ -12,13 +20,13 @@ import (
 
 func TestWebSocket(t *testing.T) {
 	// Setup a new hub
-	hub := NewHub([]string{"market"})
+	hub := NewHub([]string{"market"}, DefaultConfig())
 
-	hub.OnRegister(func(subs map[string]bool, send chan interface{}) {
+	hub.OnRegister(func(subs map[string]bool, filter *Filter, send chan interface{}) {
 		send <- "sup"
 	})
 
-	go hub.Run()
+	go hub.Run(context.Background())
 
 	// Run a webserver for the socket
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -38,12 +46,492 @@ func TestWebSocket(t *testing.T) {
 
 	c, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
 	assert.Nil(t, err)
+	marker := SnapshotMarker{}
+	err = c.ReadJSON(&marker)
+	assert.Nil(t, err)
+	assert.Equal(t, "snapshotBegin", marker.Action)
+
 	message := ""
 	err = c.ReadJSON(&message)
 	assert.Nil(t, err)
 
 	assert.Equal(t, "sup", message)
 
+	marker = SnapshotMarker{}
+	err = c.ReadJSON(&marker)
+	assert.Nil(t, err)
+	assert.Equal(t, "snapshotEnd", marker.Action)
+	assert.Equal(t, 1, marker.Payload.Messages)
+
 	err = c.Close()
 	assert.Nil(t, err)
 }
+
+func TestCommands(t *testing.T) {
+	hub := NewHub([]string{"market", "contract"}, DefaultConfig())
+	go hub.Run(context.Background())
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWs))
+	defer server.Close()
+
+	u := url.URL{Scheme: "ws", Host: server.Listener.Addr().String(), Path: "/", RawQuery: "market=1"}
+	c, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	assert.Nil(t, err)
+	defer c.Close()
+
+	ack := Reply{}
+	err = c.WriteJSON(Command{ID: "1", Action: "subscribe", Channels: []string{"contract"}})
+	assert.Nil(t, err)
+	err = c.ReadJSON(&ack)
+	assert.Nil(t, err)
+	assert.Equal(t, "ack", ack.Action)
+	assert.Equal(t, "1", ack.Payload.ID)
+	assert.Equal(t, []string{"contract", "market"}, ack.Payload.Channels)
+
+	ack = Reply{}
+	err = c.WriteJSON(Command{Action: "unsubscribe", Channels: []string{"market"}})
+	assert.Nil(t, err)
+	err = c.ReadJSON(&ack)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"contract"}, ack.Payload.Channels)
+
+	ack = Reply{}
+	err = c.WriteJSON(Command{Action: "subscribe", Channels: []string{"nope"}})
+	assert.Nil(t, err)
+	err = c.ReadJSON(&ack)
+	assert.Nil(t, err)
+	assert.Equal(t, "error", ack.Action)
+	assert.NotEmpty(t, ack.Payload.Error)
+
+	ack = Reply{}
+	err = c.WriteJSON(Command{Action: "ping"})
+	assert.Nil(t, err)
+	err = c.ReadJSON(&ack)
+	assert.Nil(t, err)
+	assert.Equal(t, "pong", ack.Action)
+
+	// Only the contract channel should get through now
+	hub.Broadcast("market", "market message")
+	hub.Broadcast("contract", "contract message")
+	message := ""
+	err = c.ReadJSON(&message)
+	assert.Nil(t, err)
+	assert.Equal(t, "contract message", message)
+}
+
+func TestShutdown(t *testing.T) {
+	hub := NewHub([]string{"market"}, DefaultConfig())
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(stopped)
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWs))
+	defer server.Close()
+
+	u := url.URL{Scheme: "ws", Host: server.Listener.Addr().String(), Path: "/", RawQuery: "market=1"}
+	c, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	assert.Nil(t, err)
+	defer c.Close()
+
+	// Round trip a command so the client is registered before stopping
+	err = c.WriteJSON(Command{Action: "ping"})
+	assert.Nil(t, err)
+	ack := Reply{}
+	err = c.ReadJSON(&ack)
+	assert.Nil(t, err)
+
+	cancel()
+
+	message := ""
+	err = c.ReadJSON(&message)
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+	<-stopped
+
+	// Broadcasts after stopping must not block
+	hub.Broadcast("market", "late")
+}
+
+func TestEncodeOnce(t *testing.T) {
+	hub := NewHub([]string{"market"}, DefaultConfig())
+	var marshalled int32
+	hub.AddEncoding("counted", Encoding{Marshal: func(m interface{}) ([]byte, error) {
+		atomic.AddInt32(&marshalled, 1)
+		return json.Marshal(m)
+	}})
+	go hub.Run(context.Background())
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWs))
+	defer server.Close()
+
+	clients := []*websocket.Conn{}
+	for _, query := range []string{"market=1&encoding=counted", "market=1&encoding=counted", "market=1"} {
+		u := url.URL{Scheme: "ws", Host: server.Listener.Addr().String(), Path: "/", RawQuery: query}
+		c, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer c.Close()
+
+		// Round trip a command so the client is registered before broadcasting
+		assert.Nil(t, c.WriteJSON(Command{Action: "ping"}))
+		assert.Nil(t, c.ReadJSON(&Reply{}))
+		clients = append(clients, c)
+	}
+	atomic.StoreInt32(&marshalled, 0)
+
+	hub.Broadcast("market", "market message")
+	for _, c := range clients {
+		message := ""
+		assert.Nil(t, c.ReadJSON(&message))
+		assert.Equal(t, "market message", message)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&marshalled))
+}
+
+// dialHub connects a websocket client to a test server for the hub
+func dialHub(t *testing.T, server *httptest.Server, query string) *websocket.Conn {
+	u := url.URL{Scheme: "ws", Host: server.Listener.Addr().String(), Path: "/", RawQuery: query}
+	c, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestSlowClient(t *testing.T) {
+	config := DefaultConfig()
+	config.SendBuffer = 2
+	hub := NewHub([]string{"market"}, config)
+	go hub.Run(context.Background())
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWs))
+	defer server.Close()
+
+	c := dialHub(t, server, "market=1")
+	assert.Nil(t, c.WriteJSON(Command{Action: "ping"}))
+	assert.Nil(t, c.ReadJSON(&Reply{}))
+
+	// Large enough to fill the socket buffers while the client is not reading
+	big := strings.Repeat("x", 1024*1024)
+	for i := 0; i < 50; i++ {
+		hub.Broadcast("market", big)
+	}
+
+	var err error
+	for err == nil {
+		_, _, err = c.ReadMessage()
+	}
+	assert.True(t, websocket.IsCloseError(err, CloseTooSlow), "%v", err)
+}
+
+func TestDumpInBackground(t *testing.T) {
+	hub := NewHub([]string{"market"}, DefaultConfig())
+	release := make(chan struct{})
+	hub.OnRegister(func(subs map[string]bool, filter *Filter, send chan interface{}) {
+		if subs["market"] {
+			<-release
+			send <- "dump"
+		}
+	})
+	go hub.Run(context.Background())
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWs))
+	defer server.Close()
+
+	// The first client's dump is stuck, which must not hold up the second
+	slow := dialHub(t, server, "market=1")
+	fast := dialHub(t, server, "snapshot=0")
+	assert.Nil(t, fast.WriteJSON(Command{Action: "subscribe", Channels: []string{"market"}}))
+	assert.Nil(t, fast.ReadJSON(&Reply{}))
+
+	hub.Broadcast("market", "broadcast")
+	message := ""
+	assert.Nil(t, fast.ReadJSON(&message))
+	assert.Equal(t, "broadcast", message)
+
+	// The dump arrives before what was broadcast while it was running
+	close(release)
+	marker := SnapshotMarker{}
+	assert.Nil(t, slow.ReadJSON(&marker))
+	assert.Equal(t, "snapshotBegin", marker.Action)
+	assert.Equal(t, uint64(0), marker.Payload.Sequence)
+	message = ""
+	assert.Nil(t, slow.ReadJSON(&message))
+	assert.Equal(t, "dump", message)
+	assert.Nil(t, slow.ReadJSON(&marker))
+	assert.Equal(t, Snapshot{Stream: hub.Stream(), Channels: []string{"market"}, Messages: 1, Items: 1}, marker.Payload)
+	message = ""
+	assert.Nil(t, slow.ReadJSON(&message))
+	assert.Equal(t, "broadcast", message)
+
+	// Or skip it
+	c := dialHub(t, server, "market=1&snapshot=0")
+	assert.Nil(t, c.WriteJSON(Command{Action: "ping"}))
+	ack := Reply{}
+	assert.Nil(t, c.ReadJSON(&ack))
+	assert.Equal(t, "pong", ack.Action)
+}
+
+// privateMessage is only sent to keys allowed private data
+type privateMessage string
+
+func (privateMessage) Private() bool { return true }
+
+func TestAuthentication(t *testing.T) {
+	config := DefaultConfig()
+	config.Keys = []APIKey{
+		{Name: "public", Key: "public-key", Channels: []string{"market"}, MaxConnections: 1},
+		{Name: "trusted", Key: "trusted-key", Private: true},
+	}
+	hub := NewHub([]string{"market", "contract"}, config)
+	go hub.Run(context.Background())
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWs))
+	defer server.Close()
+
+	dial := func(query string, header http.Header) (*websocket.Conn, int) {
+		u := url.URL{Scheme: "ws", Host: server.Listener.Addr().String(), Path: "/", RawQuery: query}
+		c, resp, err := websocket.DefaultDialer.Dial(u.String(), header)
+		if err != nil {
+			return nil, resp.StatusCode
+		}
+		t.Cleanup(func() { c.Close() })
+		return c, resp.StatusCode
+	}
+
+	_, status := dial("market=1", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	_, status = dial("market=1&key=wrong", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	_, status = dial("contract=1&key=public-key", nil)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	public, status := dial("market=1&key=public-key", nil)
+	assert.Equal(t, http.StatusSwitchingProtocols, status)
+	_, status = dial("market=1", http.Header{"X-Api-Key": {"public-key"}})
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	trusted, status := dial("market=1", http.Header{"Authorization": {"Bearer trusted-key"}})
+	assert.Equal(t, http.StatusSwitchingProtocols, status)
+
+	// Channels outside the key are refused
+	assert.Nil(t, public.WriteJSON(Command{Action: "subscribe", Channels: []string{"contract"}}))
+	ack := Reply{}
+	assert.Nil(t, public.ReadJSON(&ack))
+	assert.Equal(t, "error", ack.Action)
+	assert.Equal(t, []string{"market"}, ack.Payload.Channels)
+	assert.Nil(t, trusted.WriteJSON(Command{Action: "ping"}))
+	assert.Nil(t, trusted.ReadJSON(&ack))
+
+	// Only the trusted key receives private messages
+	hub.Broadcast("market", privateMessage("private"))
+	hub.Broadcast("market", "public")
+	message := ""
+	assert.Nil(t, trusted.ReadJSON(&message))
+	assert.Equal(t, "private", message)
+	assert.Nil(t, trusted.ReadJSON(&message))
+	assert.Equal(t, "public", message)
+	assert.Nil(t, public.ReadJSON(&message))
+	assert.Equal(t, "public", message)
+
+	// Closing a connection frees it for the key
+	public.Close()
+	assert.Eventually(t, func() bool {
+		c, _ := dial("market=1&key=public-key", nil)
+		return c != nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+// readEvent reads the next Server-Sent Event, skipping comments
+func readEvent(t *testing.T, r *bufio.Reader) (id string, data string) {
+	for {
+		line, err := r.ReadString('\n')
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && data != "":
+			return id, data
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	hub := NewHub([]string{"market", "contract"}, DefaultConfig())
+	hub.OnRegister(func(subs map[string]bool, filter *Filter, send chan interface{}) {
+		if subs["market"] {
+			send <- "dump"
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeEvents))
+	defer server.Close()
+
+	get := func(query, lastEventID string) *bufio.Reader {
+		req, _ := http.NewRequest("GET", server.URL+"/?"+query, nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		return bufio.NewReader(resp.Body)
+	}
+
+	// Messages already broadcast are not in the dump
+	hub.Broadcast("market", "before")
+
+	// Snapshot then broadcasts, with the stream position as the id
+	stream := hub.Stream()
+	events := get("market=1", "")
+	id, data := readEvent(t, events)
+	assert.Equal(t, "", id)
+	assert.JSONEq(t, `{"action": "snapshotBegin", "payload": {"stream": "`+stream+`", "seq": 1, "channels": ["market"]}}`, data)
+	_, data = readEvent(t, events)
+	assert.Equal(t, `"dump"`, data)
+	id, data = readEvent(t, events)
+	assert.Equal(t, stream+":1", id)
+	assert.JSONEq(t, `{"action": "snapshotEnd", "payload": {"stream": "`+stream+`", "seq": 1, "channels": ["market"], "messages": 1, "items": 1}}`, data)
+
+	hub.Broadcast("contract", "not subscribed")
+	hub.Broadcast("market", "first")
+	hub.Broadcast("market", "second")
+	id, data = readEvent(t, events)
+	assert.Equal(t, stream+":3", id)
+	assert.Equal(t, `"first"`, data)
+	id, data = readEvent(t, events)
+	assert.Equal(t, stream+":4", id)
+	assert.Equal(t, `"second"`, data)
+
+	// Reconnecting picks up after the last event without a dump
+	events = get("market=1", stream+":3")
+	id, data = readEvent(t, events)
+	assert.Equal(t, stream+":4", id)
+	assert.Equal(t, `"second"`, data)
+	id, _ = readEvent(t, get("market=1&stream="+stream+"&since=3", ""))
+	assert.Equal(t, stream+":4", id)
+
+	// Or gets the dump if the id is from another stream
+	_, data = readEvent(t, get("market=1", "0123456789abcdef:3"))
+	assert.Contains(t, data, "snapshotBegin")
+
+	// Other encodings are refused
+	resp, err := http.Get(server.URL + "/?market=1&encoding=msgpack")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+
+	// Streams end when the hub stops
+	cancel()
+	_, err = events.ReadString('\n')
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReplayLog(t *testing.T) {
+	l := replayLog{maxMessages: 100, maxItems: 1000}
+	for seq := uint64(1); seq <= 110; seq++ {
+		l.add(fullMessage{Channel: "market", Sequence: seq, Message: seq})
+	}
+	current := uint64(110)
+
+	missed, ok := l.since(current-3, current)
+	assert.True(t, ok)
+	assert.Len(t, missed, 3)
+	assert.Equal(t, current-2, missed[0].Sequence)
+
+	missed, ok = l.since(current, current)
+	assert.True(t, ok)
+	assert.Len(t, missed, 0)
+
+	// Aged out
+	_, ok = l.since(5, current)
+	assert.False(t, ok)
+
+	// From before a restart
+	_, ok = l.since(current+1, current)
+	assert.False(t, ok)
+}
+
+// sequenced records the stream and sequence it was stamped with
+type sequenced struct {
+	Message  string `json:"message"`
+	Stream   string `json:"stream"`
+	Sequence uint64 `json:"seq"`
+}
+
+func (m sequenced) WithSequence(stream string, seq uint64) interface{} {
+	m.Stream, m.Sequence = stream, seq
+	return m
+}
+
+func TestResumeAfterRestart(t *testing.T) {
+	start := func() (*Hub, *httptest.Server) {
+		hub := NewHub([]string{"market"}, DefaultConfig())
+		hub.OnRegister(func(subs map[string]bool, filter *Filter, send chan interface{}) {
+			send <- sequenced{Message: "dump"}
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		go hub.Run(ctx)
+		server := httptest.NewServer(http.HandlerFunc(hub.ServeWs))
+		t.Cleanup(func() {
+			server.Close()
+			cancel()
+		})
+		return hub, server
+	}
+
+	// Read up to seq 3 from the first process
+	before, server := start()
+	c := dialHub(t, server, "market=1&snapshot=0")
+	for i := 0; i < 3; i++ {
+		before.Broadcast("market", sequenced{Message: "before"})
+	}
+	last := sequenced{}
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, c.ReadJSON(&last))
+	}
+	assert.Equal(t, before.Stream(), last.Stream)
+	assert.Equal(t, uint64(3), last.Sequence)
+
+	// The restarted process has already broadcast more than that
+	after, server := start()
+	assert.NotEqual(t, before.Stream(), after.Stream())
+	for i := 0; i < 5; i++ {
+		after.Broadcast("market", sequenced{Message: "after"})
+	}
+
+	// Resuming with the old stream gets the dump, not seq 4 of the new one
+	c = dialHub(t, server, "market=1&stream="+last.Stream+"&since=3")
+	marker := SnapshotMarker{}
+	assert.Nil(t, c.ReadJSON(&marker))
+	assert.Equal(t, "snapshotBegin", marker.Action)
+	assert.Equal(t, Snapshot{Stream: after.Stream(), Sequence: 5, Channels: []string{"market"}}, marker.Payload)
+	message := sequenced{}
+	assert.Nil(t, c.ReadJSON(&message))
+	assert.Equal(t, "dump", message.Message)
+
+	// As do clients that only send since
+	c = dialHub(t, server, "market=1&since=3")
+	assert.Nil(t, c.ReadJSON(&marker))
+	assert.Equal(t, "snapshotBegin", marker.Action)
+
+	// The new stream resumes
+	c = dialHub(t, server, "market=1&stream="+after.Stream()+"&since=3")
+	assert.Nil(t, c.ReadJSON(&message))
+	assert.Equal(t, sequenced{Message: "after", Stream: after.Stream(), Sequence: 4}, message)
+}
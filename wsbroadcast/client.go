@@ -1,35 +1,47 @@
 package wsbroadcast
 
 import (
+	"encoding/json"
 	"log"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-const (
-	// Time allowed to write a message to the peer.
-	writeWait = 60 * time.Second
-
-	// Time allowed to read the next pong message from the peer.
-	pongWait = 60 * time.Second
-
-	// Send pings to peer with this period. Must be less than pongWait.
-	pingPeriod = (pongWait * 9) / 10
-)
-
 // Client is a middleman between the websocket connection and the hub.
 type Client struct {
 	hub *Hub
 
-	// The websocket connection.
+	// The websocket connection, nil for event stream clients.
 	conn *websocket.Conn
 
-	// Buffered channel of outbound messages.
-	send chan interface{}
+	// Address of the peer for logs
+	addr string
+
+	// Write Server-Sent Events rather than websocket frames
+	events bool
+
+	// Outbound messages, and the dump streamed ahead of them on connect.
+	queue *queue
+	dump  chan interface{}
 
 	// Channels available to the client
 	channels map[string]bool
+
+	// Items the client is interested in
+	filter *Filter
+
+	// Key the client connected with, nil without keys configured
+	key *APIKey
+
+	// How messages are written to the client
+	encoding Encoding
+
+	// Send the dump on connect, or resume after this sequence of the stream rather than receiving it
+	snapshot bool
+	resume   bool
+	stream   string
+	since    uint64
 }
 
 // CanSend checks if the client is subscribed to a channel
@@ -37,6 +49,17 @@ func (c *Client) CanSend(channel string) bool {
 	return c.channels[channel]
 }
 
+// filterMessage reduces a message to what the client filter allows.
+func (c *Client) filterMessage(m interface{}) (interface{}, bool) {
+	if c.filter.Empty() {
+		return m, true
+	}
+	if f, ok := m.(Filterable); ok {
+		return f.Filter(c.filter)
+	}
+	return m, true
+}
+
 // readPump pumps messages from the websocket connection to the hub.
 //
 // The application runs readPump in a per-connection goroutine. The application
@@ -44,15 +67,19 @@ func (c *Client) CanSend(channel string) bool {
 // reads from this goroutine.
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.done:
+		}
 		c.conn.Close()
+		c.hub.keys.release(c.key)
 	}()
-	c.conn.SetReadLimit(1)
+	c.conn.SetReadLimit(maxCommandSize)
+	pongWait := c.hub.config.PongWait
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
 	for {
-		// /dev/null
-		_, _, err := c.conn.ReadMessage()
+		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			log.Println(err)
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
@@ -60,6 +87,17 @@ func (c *Client) readPump() {
 			}
 			break
 		}
+
+		// Hand the command to the hub, which owns the client state.
+		cmd := Command{}
+		if err := json.Unmarshal(message, &cmd); err != nil {
+			cmd = Command{Action: "invalid"}
+		}
+		select {
+		case c.hub.commands <- clientCommand{client: c, command: cmd}:
+		case <-c.hub.done:
+			return
+		}
 	}
 }
 
@@ -69,29 +107,43 @@ func (c *Client) readPump() {
 // application ensures that there is at most one writer to a connection by
 // executing all writes from this goroutine.
 func (c *Client) writePump() {
-	ticker := time.NewTicker(pingPeriod)
+	writeWait := c.hub.config.WriteWait
+	ticker := time.NewTicker(c.hub.config.pingPeriod())
+	dump := c.dump
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		c.hub.writers.Done()
+		// Let the dump finish if we stopped part way through
+		if dump != nil {
+			go func() {
+				for range dump {
+				}
+			}()
+		}
 	}()
 	for {
 		select {
-		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		case message, ok := <-dump:
 			if !ok {
-				// The hub closed the channel.
-				err := c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				if err != nil {
-					log.Println(err)
+				// Send what was broadcast while dumping
+				dump = nil
+				if !c.flush() {
+					return
 				}
+				continue
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !c.write(message) {
 				return
 			}
 
-			// Write the object out
-
-			err := c.conn.WriteJSON(message)
-			if err != nil {
-				log.Println(err)
+		case <-c.queue.ready:
+			// Broadcasts wait for the dump unless the client is being closed
+			if dump != nil && !c.queue.isClosed() {
+				continue
+			}
+			if !c.flush() {
 				return
 			}
 
@@ -104,3 +156,58 @@ func (c *Client) writePump() {
 		}
 	}
 }
+
+// flush writes everything queued, then the close frame if the hub closed the queue.
+// Returns false once the writer should stop.
+func (c *Client) flush() bool {
+	writeWait := c.hub.config.WriteWait
+	messages, closed := c.queue.pop()
+	for _, m := range messages {
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if !c.write(m.message) {
+			return false
+		}
+	}
+	if closed {
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := c.conn.WriteMessage(websocket.CloseMessage, c.queue.closeMessage); err != nil {
+			log.Println(err)
+		}
+		return false
+	}
+	return true
+}
+
+// format names how the client writes messages. Clients with the same format
+// share one encoding of each broadcast.
+func (c *Client) format() string {
+	if c.events {
+		return "events"
+	}
+	return c.encoding.name
+}
+
+// prepare encodes a message the way the client writes it
+func (c *Client) prepare(m interface{}, seq uint64) (interface{}, error) {
+	if c.events {
+		return c.encoding.event(m, c.hub.stream, seq)
+	}
+	return c.encoding.prepare(m)
+}
+
+// write a message out, unless the hub already encoded it
+func (c *Client) write(message interface{}) bool {
+	pm, ok := message.(*websocket.PreparedMessage)
+	if !ok {
+		var err error
+		if pm, err = c.encoding.prepare(message); err != nil {
+			log.Println(err)
+			return true
+		}
+	}
+	if err := c.conn.WritePreparedMessage(pm); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package wsbroadcast
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Maximum size of a command sent by a client.
+const maxCommandSize = 64 * 1024
+
+// Command sent by a client over the open websocket.
+//
+//	{"action": "subscribe", "channels": ["market"]}
+//	{"action": "unsubscribe", "channels": ["contract"]}
+//	{"action": "filter", "region": [10000002], "type": [34, 35]}
+//	{"action": "ping"}
+type Command struct {
+	ID       string   `json:"id,omitempty"`
+	Action   string   `json:"action"`
+	Channels []string `json:"channels,omitempty"`
+	Region   []int64  `json:"region,omitempty"`
+	Location []int64  `json:"location,omitempty"`
+	Type     []int64  `json:"type,omitempty"`
+}
+
+// Ack acknowledges a command with the resulting state of the client.
+type Ack struct {
+	ID       string   `json:"id,omitempty"`
+	Command  string   `json:"command"`
+	Channels []string `json:"channels"`
+	Filter   *Filter  `json:"filter,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Reply wraps an Ack in the same frame as all other messages
+type Reply struct {
+	Action  string `json:"action"`
+	Payload Ack    `json:"payload"`
+}
+
+// clientCommand ties a command to the client who sent it
+type clientCommand struct {
+	client  *Client
+	command Command
+}
+
+// handleCommand applies a command to a client and builds the reply.
+// Must only be called from the hub goroutine.
+func (h *Hub) handleCommand(c *Client, cmd Command) Reply {
+	ack := Ack{ID: cmd.ID, Command: cmd.Action}
+	action := "ack"
+
+	var err error
+	switch cmd.Action {
+	case "subscribe":
+		err = h.setChannels(c, cmd.Channels, true)
+	case "unsubscribe":
+		err = h.setChannels(c, cmd.Channels, false)
+	case "filter":
+		c.filter = NewFilter(cmd.Region, cmd.Location, cmd.Type)
+	case "ping":
+		action = "pong"
+	default:
+		err = fmt.Errorf("unknown action %q", cmd.Action)
+	}
+
+	if err != nil {
+		action = "error"
+		ack.Error = err.Error()
+	}
+	ack.Channels = c.subscriptions()
+	if !c.filter.Empty() {
+		ack.Filter = c.filter
+	}
+
+	return Reply{Action: action, Payload: ack}
+}
+
+// setChannels turns a list of channels on or off for a client
+func (h *Hub) setChannels(c *Client, channels []string, on bool) error {
+	for _, ch := range channels {
+		if !h.hasChannel(ch) {
+			return fmt.Errorf("unknown channel %q", ch)
+		}
+		if on && !c.key.AllowChannel(ch) {
+			return fmt.Errorf("channel %q not allowed", ch)
+		}
+	}
+	for _, ch := range channels {
+		if on {
+			c.channels[ch] = true
+		} else {
+			delete(c.channels, ch)
+		}
+	}
+	return nil
+}
+
+// hasChannel checks if the hub serves a channel
+func (h *Hub) hasChannel(channel string) bool {
+	for _, c := range h.channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// subscriptions lists the channels a client is subscribed to
+func (c *Client) subscriptions() []string {
+	channels := []string{}
+	for ch, on := range c.channels {
+		if on {
+			channels = append(channels, ch)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+func idSet(ids []int64) map[int64]bool {
+	if len(ids) == 0 {
+		return nil
+	}
+	set := make(map[int64]bool)
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
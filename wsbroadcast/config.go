@@ -0,0 +1,84 @@
+package wsbroadcast
+
+import (
+	"compress/flate"
+	"errors"
+	"time"
+)
+
+// Config for the hub and its websocket clients
+type Config struct {
+	// Messages and payload items queued per client before it is dropped for
+	// falling behind
+	SendBuffer      int `yaml:"send_buffer"`
+	SendBufferItems int `yaml:"send_buffer_items"`
+
+	// Websocket buffer sizes in bytes
+	ReadBufferSize  int `yaml:"read_buffer_size"`
+	WriteBufferSize int `yaml:"write_buffer_size"`
+
+	// Per message deflate for clients that ask for it, from 1 (fastest) to 9 (smallest).
+	// Each broadcast is compressed once and shared by every client.
+	DisableCompression bool `yaml:"disable_compression"`
+	CompressionLevel   int  `yaml:"compression_level"`
+
+	// Time allowed to write a message to the peer.
+	WriteWait time.Duration `yaml:"write_wait"`
+
+	// Time allowed to read the next pong message from the peer.
+	// Pings are sent at 90% of this.
+	PongWait time.Duration `yaml:"pong_wait"`
+
+	// Most payload items in each message of the snapshot sent on connect
+	SnapshotChunk int `yaml:"snapshot_chunk"`
+
+	// API keys allowed to connect, anyone can connect when there are none
+	Keys []APIKey `yaml:"keys"`
+
+	// Origins browsers may connect from, as hosts or full origins. Any when empty.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+
+	// Broadcasts and payload items kept for clients resuming with ?since=
+	ReplayMessages int `yaml:"replay_messages"`
+	ReplayItems    int `yaml:"replay_items"`
+}
+
+// DefaultConfig returns the settings the hub has always used
+func DefaultConfig() Config {
+	return Config{
+		SendBuffer:       256,
+		SendBufferItems:  1000000,
+		ReadBufferSize:   1024,
+		WriteBufferSize:  1024 * 1024 * 500,
+		CompressionLevel: flate.BestSpeed,
+		WriteWait:        60 * time.Second,
+		PongWait:         60 * time.Second,
+		SnapshotChunk:    10000,
+		ReplayMessages:   1024,
+		ReplayItems:      1000000,
+	}
+}
+
+// Validate checks the settings are usable
+func (c *Config) Validate() error {
+	switch {
+	case c.SendBuffer < 1 || c.SendBufferItems < 1:
+		return errors.New("websocket send_buffer and send_buffer_items must be positive")
+	case c.ReadBufferSize < 1 || c.WriteBufferSize < 1:
+		return errors.New("websocket buffer sizes must be positive")
+	case c.WriteWait <= 0 || c.PongWait <= 0:
+		return errors.New("websocket write_wait and pong_wait must be positive")
+	case c.CompressionLevel < flate.BestSpeed || c.CompressionLevel > flate.BestCompression:
+		return errors.New("websocket compression_level must be between 1 and 9")
+	case c.SnapshotChunk < 1:
+		return errors.New("websocket snapshot_chunk must be positive")
+	case c.ReplayMessages < 0 || c.ReplayItems < 0:
+		return errors.New("websocket replay limits cannot be negative")
+	}
+	return validateKeys(c.Keys)
+}
+
+// pingPeriod sends pings to peer with this period. Must be less than pongWait.
+func (c *Config) pingPeriod() time.Duration {
+	return (c.PongWait * 9) / 10
+}
@@ -0,0 +1,82 @@
+package wsbroadcast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoding turns messages into websocket frames. Clients pick one by name
+// with ?encoding= or by requesting it as the websocket subprotocol.
+// Commands from clients are always JSON.
+type Encoding struct {
+	// Binary frames rather than text
+	Binary bool
+
+	// Marshal a broadcast or a Reply to a command
+	Marshal func(m interface{}) ([]byte, error)
+
+	// name the encoding was added under
+	name string
+}
+
+// JSON is the default encoding
+var JSON = Encoding{Marshal: json.Marshal}
+
+// MsgPack encodes with MessagePack using the same field names as JSON.
+// Times are MessagePack timestamps.
+var MsgPack = Encoding{Binary: true, Marshal: marshalMsgPack}
+
+func marshalMsgPack(m interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := msgpack.NewEncoder(&b)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// frameType is the websocket message type the encoding writes
+func (e Encoding) frameType() int {
+	if e.Binary {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// prepare encodes a message once so it can be written to any number of
+// clients. Compressed frames are built once per compression level on first use.
+func (e Encoding) prepare(m interface{}) (*websocket.PreparedMessage, error) {
+	data, err := e.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return websocket.NewPreparedMessage(e.frameType(), data)
+}
+
+// AddEncoding makes another encoding available to clients.
+// Must be called before the hub serves any client.
+func (h *Hub) AddEncoding(name string, e Encoding) {
+	if _, ok := h.encodings[name]; !ok {
+		h.upgrader.Subprotocols = append(h.upgrader.Subprotocols, name)
+	}
+	e.name = name
+	h.encodings[name] = e
+}
+
+// encoding looks up an encoding by name, the default if empty
+func (h *Hub) encoding(name string) (Encoding, error) {
+	if name == "" {
+		name = "json"
+	}
+	e, ok := h.encodings[name]
+	if !ok {
+		return Encoding{}, fmt.Errorf("unknown encoding %q", name)
+	}
+	return e, nil
+}
@@ -0,0 +1,186 @@
+package wsbroadcast
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// event is a message formatted as a Server-Sent Event
+type event []byte
+
+// event formats a message as a Server-Sent Event. Broadcasts carry the stream
+// and their sequence as the id, stream:seq, so a reconnecting EventSource
+// resumes where it left off.
+func (e Encoding) event(m interface{}, stream string, seq uint64) (event, error) {
+	data, err := e.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if seq != 0 {
+		b.WriteString("id: " + stream + ":" + strconv.FormatUint(seq, 10) + "\n")
+	}
+	b.WriteString("data: ")
+	b.Write(data)
+	b.WriteString("\n\n")
+	return b.Bytes(), nil
+}
+
+// ServeEvents streams broadcasts as Server-Sent Events for clients that cannot
+// hold a websocket. Channels, filters and the snapshot are picked as for ServeWs
+// but cannot be changed once the stream starts. Events are always JSON.
+// Clients resume from the Last-Event-ID header, or ?stream=&since= on the first request.
+func (h *Hub) ServeEvents(w http.ResponseWriter, r *http.Request) {
+	key, err := h.keys.authenticate(r)
+	if err != nil {
+		authError(w, err)
+		return
+	}
+	defer h.keys.release(key)
+
+	stream, since := r.URL.Query().Get("stream"), r.URL.Query().Get("since")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		stream, since, _ = strings.Cut(id, ":")
+	}
+	client, ok := h.newClient(w, r, key, stream, since)
+	if !ok {
+		return
+	}
+	if name := r.URL.Query().Get("encoding"); name != "" && name != "json" {
+		http.Error(w, "encoding: events are always json", http.StatusBadRequest)
+		return
+	}
+	client.events = true
+	client.encoding, _ = h.encoding("json")
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stop nginx buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		log.Println(err)
+		return
+	}
+
+	select {
+	case h.register <- client:
+	case <-h.done:
+		return
+	case <-r.Context().Done():
+		return
+	}
+	defer func() {
+		select {
+		case h.unregister <- client:
+		case <-h.done:
+		}
+	}()
+	client.streamEvents(r.Context(), w, rc)
+}
+
+// streamEvents writes the dump and then the broadcasts to an event stream,
+// like writePump does for a websocket, until the request ends or the hub
+// closes the client.
+func (c *Client) streamEvents(ctx context.Context, w io.Writer, rc *http.ResponseController) {
+	writeWait := c.hub.config.WriteWait
+	ticker := time.NewTicker(c.hub.config.pingPeriod())
+	dump := c.dump
+	defer func() {
+		ticker.Stop()
+		c.hub.writers.Done()
+		// Let the dump finish if we stopped part way through
+		if dump != nil {
+			go func() {
+				for range dump {
+				}
+			}()
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case message, ok := <-dump:
+			if !ok {
+				// Send what was broadcast while dumping
+				dump = nil
+				if !c.flushEvents(w, rc) {
+					return
+				}
+				continue
+			}
+
+			// Reconnecting after the dump resumes from the broadcasts that followed it
+			var seq uint64
+			if marker, ok := message.(SnapshotMarker); ok && marker.Action == "snapshotEnd" {
+				seq = marker.Payload.Sequence
+			}
+			rc.SetWriteDeadline(time.Now().Add(writeWait))
+			if !c.writeEvent(w, message, seq) {
+				return
+			}
+			if len(dump) == 0 && rc.Flush() != nil {
+				return
+			}
+
+		case <-c.queue.ready:
+			// Broadcasts wait for the dump unless the client is being closed
+			if dump != nil && !c.queue.isClosed() {
+				continue
+			}
+			if !c.flushEvents(w, rc) {
+				return
+			}
+
+		case <-ticker.C:
+			// A comment keeps proxies from timing out a quiet stream
+			rc.SetWriteDeadline(time.Now().Add(writeWait))
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+// flushEvents writes everything queued. Returns false once the stream should end,
+// because it failed or the hub closed the queue.
+func (c *Client) flushEvents(w io.Writer, rc *http.ResponseController) bool {
+	messages, closed := c.queue.pop()
+	for _, m := range messages {
+		rc.SetWriteDeadline(time.Now().Add(c.hub.config.WriteWait))
+		if !c.writeEvent(w, m.message, m.seq) {
+			return false
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		log.Println(err)
+		return false
+	}
+	return !closed
+}
+
+// writeEvent writes a message out, unless the hub already formatted it
+func (c *Client) writeEvent(w io.Writer, message interface{}, seq uint64) bool {
+	e, ok := message.(event)
+	if !ok {
+		var err error
+		if e, err = c.encoding.event(message, c.hub.stream, seq); err != nil {
+			log.Println(err)
+			return true
+		}
+	}
+	if _, err := w.Write(e); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
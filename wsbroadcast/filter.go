@@ -0,0 +1,137 @@
+package wsbroadcast
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Filter narrows down what a client receives on its channels.
+// An empty set matches everything.
+type Filter struct {
+	Regions   map[int64]bool
+	Locations map[int64]bool
+	Types     map[int64]bool
+}
+
+// Filterable messages can reduce themselves to what a filter allows.
+type Filterable interface {
+	// Filter returns the reduced message, or false if nothing is left to send.
+	Filter(f *Filter) (interface{}, bool)
+}
+
+// ParseFilter reads the region, location and type query parameters.
+// Each may be repeated or contain a comma separated list of IDs.
+func ParseFilter(q url.Values) (*Filter, error) {
+	var err error
+	f := &Filter{}
+	if f.Regions, err = parseIDs(q["region"]); err != nil {
+		return nil, fmt.Errorf("region: %v", err)
+	}
+	if f.Locations, err = parseIDs(q["location"]); err != nil {
+		return nil, fmt.Errorf("location: %v", err)
+	}
+	if f.Types, err = parseIDs(q["type"]); err != nil {
+		return nil, fmt.Errorf("type: %v", err)
+	}
+	return f, nil
+}
+
+// NewFilter builds a filter from lists of IDs, such as those in the filter command
+func NewFilter(regions, locations, types []int64) *Filter {
+	return &Filter{
+		Regions:   idSet(regions),
+		Locations: idSet(locations),
+		Types:     idSet(types),
+	}
+}
+
+// filterLists is the filter in the same shape as the filter command
+type filterLists struct {
+	Region   []int64 `json:"region,omitempty" msgpack:"region,omitempty"`
+	Location []int64 `json:"location,omitempty" msgpack:"location,omitempty"`
+	Type     []int64 `json:"type,omitempty" msgpack:"type,omitempty"`
+}
+
+// IDs lists the regions, locations and types in the filter, sorted
+func (f *Filter) IDs() (regions, locations, types []int64) {
+	return idList(f.Regions), idList(f.Locations), idList(f.Types)
+}
+
+// MarshalJSON sends the filter back in the same shape as the filter command
+func (f *Filter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.lists())
+}
+
+// MarshalMsgpack sends the filter back in the same shape as the filter command
+func (f *Filter) MarshalMsgpack() ([]byte, error) {
+	return msgpack.Marshal(f.lists())
+}
+
+func (f *Filter) lists() filterLists {
+	l := filterLists{}
+	l.Region, l.Location, l.Type = f.IDs()
+	return l
+}
+
+func idList(ids map[int64]bool) []int64 {
+	list := []int64{}
+	for id, on := range ids {
+		if on {
+			list = append(list, id)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+func parseIDs(values []string) (map[int64]bool, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	ids := make(map[int64]bool)
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			ids[id] = true
+		}
+	}
+	return ids, nil
+}
+
+// Empty is true when the filter lets everything through.
+func (f *Filter) Empty() bool {
+	return f == nil || (len(f.Regions) == 0 && len(f.Locations) == 0 && len(f.Types) == 0)
+}
+
+// MatchRegion checks a region ID against the filter
+func (f *Filter) MatchRegion(regionID int64) bool {
+	return f == nil || len(f.Regions) == 0 || f.Regions[regionID]
+}
+
+// MatchLocation checks a location ID against the filter
+func (f *Filter) MatchLocation(locationID int64) bool {
+	return f == nil || len(f.Locations) == 0 || f.Locations[locationID]
+}
+
+// MatchType checks a type ID against the filter
+func (f *Filter) MatchType(typeID int64) bool {
+	return f == nil || len(f.Types) == 0 || f.Types[typeID]
+}
+
+// Match checks a single item against the whole filter
+func (f *Filter) Match(regionID, locationID, typeID int64) bool {
+	return f.MatchRegion(regionID) && f.MatchLocation(locationID) && f.MatchType(typeID)
+}
@@ -0,0 +1,30 @@
+package wsbroadcast
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	q, err := url.ParseQuery("market=1&region=10000002&type=34,35&type=36")
+	assert.Nil(t, err)
+
+	f, err := ParseFilter(q)
+	assert.Nil(t, err)
+	assert.False(t, f.Empty())
+	assert.True(t, f.Match(10000002, 60003760, 34))
+	assert.True(t, f.Match(10000002, 60003760, 36))
+	assert.False(t, f.Match(10000043, 60003760, 34))
+	assert.False(t, f.Match(10000002, 60003760, 37))
+
+	q, err = url.ParseQuery("market=1")
+	assert.Nil(t, err)
+	f, err = ParseFilter(q)
+	assert.Nil(t, err)
+	assert.True(t, f.Empty())
+
+	_, err = ParseFilter(url.Values{"location": []string{"jita"}})
+	assert.NotNil(t, err)
+}
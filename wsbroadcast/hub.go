@@ -1,19 +1,27 @@
 package wsbroadcast
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
 // HandlerFunc is used for callbacks
-// sends a list of channels the client registered to and a return channel
-type HandlerFunc func(map[string]bool, chan interface{})
+// sends a list of channels the client registered to, the client filter and a return channel
+type HandlerFunc func(map[string]bool, *Filter, chan interface{})
 
 type fullMessage struct {
-	Channel string
-	Message interface{}
+	Channel  string
+	Sequence uint64
+	Message  interface{}
 }
 
 // Hub maintains the set of active clients and broadcasts messages to the
@@ -31,100 +39,339 @@ type Hub struct {
 	// Unregister requests from clients.
 	unregister chan *Client
 
+	// Commands from clients.
+	commands chan clientCommand
+
 	// onRegister callbacks
 	onRegister []HandlerFunc
 
 	// which channels are available to register for
 	channels []string
+
+	// position in the stream and recent broadcasts for resuming clients.
+	// The stream ID changes on every start so sequences from before a
+	// restart are never mistaken for this stream's.
+	stream   string
+	sequence uint64
+	replay   replayLog
+
+	// settings and the upgrader built from them
+	config   Config
+	upgrader websocket.Upgrader
+
+	// encodings clients can ask for by name
+	encodings map[string]Encoding
+
+	// API keys and their open connections
+	keys keys
+
+	// closed when the hub stops, and the client writers still flushing
+	done    chan struct{}
+	writers sync.WaitGroup
 }
 
 // NewHub Create a new hub for the handler
-func NewHub(availableChannels []string) *Hub {
-	return &Hub{
+func NewHub(availableChannels []string, config Config) *Hub {
+	h := &Hub{
 		broadcast:  make(chan fullMessage),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		commands:   make(chan clientCommand),
 		clients:    make(map[*Client]bool),
+		done:       make(chan struct{}),
 		channels:   availableChannels,
+		stream:     newStreamID(),
+		replay: replayLog{
+			maxMessages: config.ReplayMessages,
+			maxItems:    config.ReplayItems,
+		},
+		config: config,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:    config.ReadBufferSize,
+			WriteBufferSize:   config.WriteBufferSize,
+			CheckOrigin:       config.checkOrigin,
+			EnableCompression: !config.DisableCompression,
+		},
+		encodings: make(map[string]Encoding),
+		keys: keys{
+			list:        config.Keys,
+			connections: make(map[string]int),
+		},
 	}
+	h.AddEncoding("json", JSON)
+	h.AddEncoding("msgpack", MsgPack)
+	return h
+}
+
+// newStreamID returns a random ID for a hub's stream
+func newStreamID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Stream is the ID clients resume with alongside a sequence
+func (h *Hub) Stream() string {
+	return h.stream
 }
 
-// Broadcast message to the clients
+// Broadcast message to the clients. Messages are dropped once the hub has stopped.
 func (h *Hub) Broadcast(channel string, m interface{}) {
-	h.broadcast <- fullMessage{channel, m}
+	select {
+	case h.broadcast <- fullMessage{Channel: channel, Message: m}:
+	case <-h.done:
+	}
 }
 
-// OnRegister calls a handler when a client registers.
+// OnRegister calls a handler when a client registers. Handlers run on their own
+// goroutine and what they send is streamed to the client before any broadcasts.
 func (h *Hub) OnRegister(f HandlerFunc) {
 	h.onRegister = append(h.onRegister, f)
 }
 
-// Run the hub
-func (h *Hub) Run() {
+// Run the hub until the context is cancelled, then close every client with
+// a going away frame once it has flushed what it was sent.
+func (h *Hub) Run(ctx context.Context) {
+	defer h.writers.Wait()
 	for {
 		select {
+		case <-ctx.Done():
+			close(h.done)
+			for client := range h.clients {
+				client.queue.close(websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutting down"))
+				delete(h.clients, client)
+			}
+			return
 		case client := <-h.register:
 			h.clients[client] = true
-			for _, c := range h.onRegister {
-				c(client.channels, client.send)
+			h.writers.Add(1)
+			if client.resume && h.resume(client) {
+				close(client.dump)
+				continue
 			}
+			h.dump(client)
 		case client := <-h.unregister:
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
+				client.queue.drop(nil)
+			}
+		case cmd := <-h.commands:
+			if _, ok := h.clients[cmd.client]; ok {
+				h.send(cmd.client, h.handleCommand(cmd.client, cmd.command), 0, 1)
 			}
 		case message := <-h.broadcast:
+			h.sequence++
+			message.Sequence = h.sequence
+			if m, ok := message.Message.(Sequencer); ok {
+				message.Message = m.WithSequence(h.stream, h.sequence)
+			}
+			h.replay.add(message)
+
+			// Clients without a filter share one encoding of the message,
+			// filtered ones encode their own copy in their writer.
+			prepared := make(map[string]interface{})
+			items := messageLen(message.Message)
 			for client := range h.clients {
-				if client.CanSend(message.Channel) {
-					select {
-					case client.send <- message.Message:
-					default:
-						close(client.send)
-						delete(h.clients, client)
+				if !client.CanSend(message.Channel) || !client.allowMessage(message.Message) {
+					continue
+				}
+				if !client.filter.Empty() {
+					if m, ok := client.filterMessage(message.Message); ok {
+						h.send(client, m, message.Sequence, messageLen(m))
 					}
+					continue
 				}
+
+				pm, ok := prepared[client.format()]
+				if !ok {
+					var err error
+					if pm, err = client.prepare(message.Message, message.Sequence); err != nil {
+						log.Println(err)
+						continue
+					}
+					prepared[client.format()] = pm
+				}
+				h.send(client, pm, message.Sequence, items)
+			}
+		}
+	}
+}
+
+// resume sends a reconnecting client the broadcasts it missed.
+// Returns false if they have aged out, or were from a stream before a restart,
+// and a full dump is needed.
+func (h *Hub) resume(client *Client) bool {
+	if client.stream != h.stream {
+		return false
+	}
+	missed, ok := h.replay.since(client.since, h.sequence)
+	if !ok {
+		return false
+	}
+	for _, message := range missed {
+		if client.CanSend(message.Channel) && client.allowMessage(message.Message) {
+			if m, ok := client.filterMessage(message.Message); ok {
+				h.send(client, m, message.Sequence, messageLen(m))
 			}
 		}
 	}
+	return true
+}
+
+// dump streams what the register handlers send to a new client from another
+// goroutine, so a large dump to one client never holds up broadcasts to the rest.
+// The dump is framed by snapshotBegin and snapshotEnd. Broadcasts queue up
+// behind it and may repeat some of what it contained.
+func (h *Hub) dump(client *Client) {
+	if len(h.onRegister) == 0 || !client.snapshot {
+		close(client.dump)
+		return
+	}
+
+	// The handlers must not see later changes made by commands
+	channels := make(map[string]bool, len(client.channels))
+	for ch, on := range client.channels {
+		channels[ch] = on
+	}
+
+	handlers, filter, dump := h.onRegister, client.filter, client.dump
+	snapshot := Snapshot{Stream: h.stream, Sequence: h.sequence, Channels: client.subscriptions()}
+	go func() {
+		defer close(dump)
+		dump <- SnapshotMarker{Action: "snapshotBegin", Payload: snapshot}
+
+		messages := make(chan interface{})
+		go func() {
+			defer close(messages)
+			for _, f := range handlers {
+				f(channels, filter, messages)
+			}
+		}()
+		for m := range messages {
+			if !client.allowMessage(m) {
+				continue
+			}
+			snapshot.Messages++
+			snapshot.Items += messageLen(m)
+			dump <- m
+		}
+
+		dump <- SnapshotMarker{Action: "snapshotEnd", Payload: snapshot}
+	}()
 }
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024 * 1024 * 500,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-	EnableCompression: true,
+// send a message to a client, dropping the client if it has fallen too far behind
+func (h *Hub) send(client *Client, m interface{}, seq uint64, items int) {
+	if client.queue.push(m, seq, items) {
+		return
+	}
+	log.Printf("dropping client %s: too far behind", client.addr)
+	metricDropped.Inc()
+	client.queue.drop(websocket.FormatCloseMessage(CloseTooSlow, "too far behind, reconnect with since"))
+	delete(h.clients, client)
 }
 
 // ServeWs handles websocket requests from the peer.
 func (h *Hub) ServeWs(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	key, err := h.keys.authenticate(r)
+	if err != nil {
+		authError(w, err)
+		return
+	}
+
+	// Hold the connection slot until the client has started
+	started := false
+	defer func() {
+		if !started {
+			h.keys.release(key)
+		}
+	}()
+
+	// read the subscriptions before upgrading so we can still reply with an error
+	client, ok := h.newClient(w, r, key, r.URL.Query().Get("stream"), r.URL.Query().Get("since"))
+	if !ok {
+		return
+	}
+
+	// pick the encoding by name, falling back to the subprotocol after upgrading
+	name := r.URL.Query().Get("encoding")
+	client.encoding, err = h.encoding(name)
+	if err != nil {
+		http.Error(w, "encoding: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println(err)
 		return
 	}
+	conn.SetCompressionLevel(h.config.CompressionLevel)
+	if name == "" {
+		client.encoding, _ = h.encoding(conn.Subprotocol())
+	}
+	client.conn = conn
+
+	select {
+	case client.hub.register <- client:
+	case <-h.done:
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutting down"),
+			time.Now().Add(h.config.WriteWait))
+		conn.Close()
+		return
+	}
+
+	// Allow collection of memory referenced by the caller by doing all work in
+	// new goroutines.
+	started = true
+	go client.writePump()
+	go client.readPump()
+}
+
+// newClient reads the subscriptions and the snapshot options every kind of
+// client shares from a request, replying with an error if they are invalid.
+// since is the sequence to resume after, if any, in the stream with that ID.
+func (h *Hub) newClient(w http.ResponseWriter, r *http.Request, key *APIKey, stream, since string) (*Client, bool) {
+	filter, err := ParseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
 
 	// get a list of subscription requests
 	channels := make(map[string]bool)
 	for _, c := range h.channels {
 		if r.URL.Query().Get(c) != "" {
+			if !key.AllowChannel(c) {
+				http.Error(w, fmt.Sprintf("channel %q not allowed", c), http.StatusForbidden)
+				return nil, false
+			}
 			channels[c] = true
 		}
 	}
 
-	// Create a new client
+	// skip the dump, or resume from a sequence instead of receiving it
 	client := &Client{
 		hub:      h,
-		conn:     conn,
-		send:     make(chan interface{}, 256),
+		addr:     r.RemoteAddr,
+		queue:    newQueue(h.config.SendBuffer, h.config.SendBufferItems),
+		dump:     make(chan interface{}, h.config.SendBuffer),
 		channels: channels,
+		filter:   filter,
+		key:      key,
+		snapshot: r.URL.Query().Get("snapshot") != "0",
+		resume:   since != "",
+		stream:   stream,
 	}
-
-	client.hub.register <- client
-
-	// Allow collection of memory referenced by the caller by doing all work in
-	// new goroutines.
-	go client.writePump()
-	go client.readPump()
+	if client.resume {
+		client.since, err = strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			http.Error(w, "since: "+err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	return client, true
 }
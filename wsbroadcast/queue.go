@@ -0,0 +1,147 @@
+package wsbroadcast
+
+import (
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CloseTooSlow is the close code sent to a client dropped for falling behind.
+// It can reconnect with ?since= to pick up the messages it missed.
+const CloseTooSlow = websocket.CloseTryAgainLater
+
+// queue holds messages for a client until its writer sends them.
+// The hub adds to it without ever blocking, and drops the client once
+// either limit is reached.
+type queue struct {
+	mutex    sync.Mutex
+	messages []queued
+	items    int
+
+	maxMessages int
+	maxItems    int
+
+	// set once the client is done with, the writer sends closeMessage after what is left
+	closed       bool
+	closeMessage []byte
+
+	// signalled when messages are added or the queue is closed
+	ready chan struct{}
+}
+
+// queued is a message, its position in the stream if it was broadcast and
+// the number of items it carries
+type queued struct {
+	message interface{}
+	seq     uint64
+	items   int
+}
+
+func newQueue(maxMessages, maxItems int) *queue {
+	return &queue{
+		maxMessages: maxMessages,
+		maxItems:    maxItems,
+		ready:       make(chan struct{}, 1),
+	}
+}
+
+// push adds a message, returning false if the client is too far behind to take it
+func (q *queue) push(m interface{}, seq uint64, items int) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return true
+	}
+
+	// A single message over the item limit still goes to a client that has caught up
+	if len(q.messages) >= q.maxMessages || (len(q.messages) > 0 && q.items+items > q.maxItems) {
+		return false
+	}
+
+	q.messages = append(q.messages, queued{message: m, seq: seq, items: items})
+	q.items += items
+	metricQueued.Inc()
+	metricQueueDepth.Observe(float64(len(q.messages)))
+	q.signal()
+	return true
+}
+
+// pop takes everything queued, and whether the queue has been closed
+func (q *queue) pop() ([]queued, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	messages := q.messages
+	q.messages = nil
+	q.items = 0
+	metricQueued.Sub(float64(len(messages)))
+	return messages, q.closed
+}
+
+// close the queue once what is already in it has been sent
+func (q *queue) close(closeMessage []byte) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	q.closeMessage = closeMessage
+	q.signal()
+}
+
+// drop everything queued and close the queue
+func (q *queue) drop(closeMessage []byte) {
+	q.mutex.Lock()
+	metricQueued.Sub(float64(len(q.messages)))
+	q.messages = nil
+	q.items = 0
+	q.mutex.Unlock()
+	q.close(closeMessage)
+}
+
+// isClosed checks if the queue has been closed
+func (q *queue) isClosed() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.closed
+}
+
+// signal the writer without waiting, one pending signal is enough
+func (q *queue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Metrics
+var (
+	metricQueued = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "evemarketwatch",
+		Subsystem: "websocket",
+		Name:      "queued",
+		Help:      "Messages waiting to be written to websocket clients.",
+	})
+	metricQueueDepth = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "evemarketwatch",
+		Subsystem: "websocket",
+		Name:      "queue_depth",
+		Help:      "Depth of a client queue each time a message is added to it.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	})
+	metricDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "evemarketwatch",
+		Subsystem: "websocket",
+		Name:      "dropped",
+		Help:      "Clients dropped for falling too far behind.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		metricQueued,
+		metricQueueDepth,
+		metricDropped,
+	)
+}
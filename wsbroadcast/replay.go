@@ -0,0 +1,53 @@
+package wsbroadcast
+
+// Sequencer messages can be stamped with their position in the stream.
+type Sequencer interface {
+	// WithSequence returns a copy of the message carrying the stream ID and sequence number.
+	WithSequence(stream string, seq uint64) interface{}
+}
+
+// Sizer messages report how many items they carry so the replay log can be bounded.
+type Sizer interface {
+	Len() int
+}
+
+// replayLog keeps the most recent broadcasts in order.
+type replayLog struct {
+	messages    []fullMessage
+	items       int
+	maxMessages int
+	maxItems    int
+}
+
+// add a broadcast to the log, dropping the oldest ones when full
+func (l *replayLog) add(m fullMessage) {
+	l.messages = append(l.messages, m)
+	l.items += messageLen(m.Message)
+	for len(l.messages) > 0 && (len(l.messages) > l.maxMessages || l.items > l.maxItems) {
+		l.items -= messageLen(l.messages[0].Message)
+		l.messages[0] = fullMessage{}
+		l.messages = l.messages[1:]
+	}
+}
+
+// since returns all broadcasts after seq, or false if they are no longer available.
+func (l *replayLog) since(seq, current uint64) ([]fullMessage, bool) {
+	if seq > current {
+		return nil, false
+	}
+	if seq == current {
+		return nil, true
+	}
+	if len(l.messages) == 0 || l.messages[0].Sequence > seq+1 {
+		return nil, false
+	}
+	first := len(l.messages) - int(current-seq)
+	return l.messages[first:], true
+}
+
+func messageLen(m interface{}) int {
+	if s, ok := m.(Sizer); ok {
+		return s.Len()
+	}
+	return 1
+}
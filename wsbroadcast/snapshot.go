@@ -0,0 +1,27 @@
+package wsbroadcast
+
+// SnapshotMarker frames the dump sent on connect so clients know where it
+// ends and live broadcasts begin.
+//
+//	{"action": "snapshotBegin", "payload": {"stream": "9f86d081884c7d65", "seq": 1234, "channels": ["market"]}}
+//	{"action": "snapshotEnd", "payload": {"stream": "9f86d081884c7d65", "seq": 1234, "channels": ["market"], "messages": 12, "items": 105000}}
+type SnapshotMarker struct {
+	Action  string   `json:"action"`
+	Payload Snapshot `json:"payload"`
+}
+
+// Snapshot describes the dump
+type Snapshot struct {
+	// The stream the sequence belongs to, which changes when the server restarts
+	Stream string `json:"stream"`
+
+	// Broadcasts up to this sequence are included in the dump, later ones follow it
+	Sequence uint64 `json:"seq"`
+
+	// Channels the dump covers
+	Channels []string `json:"channels"`
+
+	// Messages and payload items in the dump, set on snapshotEnd
+	Messages int `json:"messages,omitempty"`
+	Items    int `json:"items,omitempty"`
+}